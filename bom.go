@@ -0,0 +1,136 @@
+// bom.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+const bomAPIURL = "https://sws-data.sws.bom.gov.au/api/v1/"
+
+// bomK is one K index value from the Bureau of Meteorology Space Weather
+// Services API
+type bomK struct {
+	Index     float64 `json:"index"`
+	ValidTime string  `json:"valid_time"`
+}
+
+// bomAuroraNotice is an aurora alert or watch; alerts carry description,
+// watches comments
+type bomAuroraNotice struct {
+	StartTime   string  `json:"start_time"`
+	StartDate   string  `json:"start_date"`
+	KAus        float64 `json:"k_aus"`
+	LatBand     string  `json:"lat_band"`
+	Description string  `json:"description"`
+	Comments    string  `json:"comments"`
+}
+
+// bomPost calls an SWS API endpoint; every request carries the API key and
+// responses wrap their records in data
+func bomPost(source, endpoint string, options map[string]string, target interface{}) error {
+	if config.BoMAPIKey == "" {
+		return fmt.Errorf("bom_api_key is not set")
+	}
+	payload := map[string]interface{}{"api_key": config.BoMAPIKey}
+	if options != nil {
+		payload["options"] = options
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", bomAPIURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return fetchRequest(source, req, func(r io.Reader) error {
+		var resp struct {
+			Data   json.RawMessage `json:"data"`
+			Errors []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		if err := json.NewDecoder(r).Decode(&resp); err != nil {
+			return err
+		}
+		if len(resp.Errors) > 0 {
+			return fmt.Errorf("%s: %s", endpoint, resp.Errors[0].Message)
+		}
+		if len(resp.Data) == 0 {
+			return nil
+		}
+		return json.Unmarshal(resp.Data, target)
+	})
+}
+
+// fetchBoMKIndex is the bom_k_index provider: the K index for bom_location
+// (by default the Australian region) over the last day
+func fetchBoMKIndex() ([]Sample, error) {
+	now := time.Now().UTC()
+	var ks []bomK
+	err := bomPost("bom_k_index", "get-k-index", map[string]string{
+		"location": config.BoMLocation,
+		"start":    now.Add(-24 * time.Hour).Format("2006-01-02 15:04:05"),
+		"end":      now.Format("2006-01-02 15:04:05"),
+	}, &ks)
+	if err != nil {
+		return nil, err
+	}
+	samples := make([]Sample, 0, len(ks))
+	for _, k := range ks {
+		samples = append(samples, Sample{TimeTag: k.ValidTime, Value: k.Index})
+	}
+	sort.Slice(samples, func(i, j int) bool { return samples[i].TimeTag < samples[j].TimeTag })
+	if len(samples) > 0 {
+		recordTimeTag("bom_k_index", samples[len(samples)-1].TimeTag)
+	}
+	return samples, nil
+}
+
+// processBoMAurora sends current Australian aurora alerts and watches
+func processBoMAurora(store StateStore) (Severity, error) {
+	var alerts, watches []bomAuroraNotice
+	if err := bomPost("bom_aurora", "get-aurora-alert", nil, &alerts); err != nil {
+		log.Println("Error fetching BoM aurora alerts:", err)
+		return SeverityOK, err
+	}
+	if err := bomPost("bom_aurora", "get-aurora-watch", nil, &watches); err != nil {
+		log.Println("Error fetching BoM aurora watches:", err)
+		return SeverityOK, err
+	}
+	notes := evaluateBoMAurora(alerts, watches)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+func evaluateBoMAurora(alerts, watches []bomAuroraNotice) []Notification {
+	var notes []Notification
+	add := func(kind string, a bomAuroraNotice) {
+		start := a.StartTime
+		if start == "" {
+			start = a.StartDate
+		}
+		detail := strings.TrimSpace(a.Description + " " + a.Comments)
+		msg := renderMessage("bom_aurora", map[string]interface{}{
+			"Kind": kind, "KAus": a.KAus, "LatBand": a.LatBand, "Start": start, "Detail": detail,
+		})
+		issued, _ := parseTimeTag(start)
+		notes = append(notes, Notification{Key: hashAlert(msg), Rule: "bom_aurora", Text: msg, Severity: SeverityWarning, Time: issued})
+	}
+	for _, a := range alerts {
+		add("alert", a)
+	}
+	for _, w := range watches {
+		add("watch", w)
+	}
+	return notes
+}
@@ -0,0 +1,46 @@
+// bz_flip.go
+package main
+
+import "time"
+
+// evaluateBzFlip alerts when Bz has turned from northward to southward by at
+// least bz_flip_delta_nt within bz_flip_window_minutes, which often comes
+// just before a storm intensifies and can happen well above bz_threshold
+func evaluateBzFlip(bzList []Sample) []Notification {
+	if config.BzFlipDelta <= 0 || len(bzList) < 2 {
+		return nil
+	}
+	latest := bzList[len(bzList)-1]
+	if latest.Value >= 0 {
+		return nil
+	}
+	observed, err := parseTimeTag(latest.TimeTag)
+	if err != nil {
+		return nil
+	}
+	window := time.Duration(config.BzFlipWindow) * time.Minute
+	var peak *Sample
+	for i := len(bzList) - 2; i >= 0; i-- {
+		t, err := parseTimeTag(bzList[i].TimeTag)
+		if err != nil || observed.Sub(t) > window {
+			break
+		}
+		if s := bzList[i]; s.Value > 0 && (peak == nil || s.Value > peak.Value) {
+			peak = &bzList[i]
+		}
+	}
+	if peak == nil || peak.Value-latest.Value < config.BzFlipDelta {
+		return nil
+	}
+
+	severity := SeverityWarning
+	if latest.Value < config.BzCriticalThreshold {
+		severity = SeverityCritical
+	}
+	msg := renderMessage("bz_flip", map[string]interface{}{
+		"From": peak.Value, "To": latest.Value, "FromTimeTag": peak.TimeTag, "TimeTag": latest.TimeTag,
+	})
+	// keyed on the northward peak so a flip is sent once, not every minute
+	// while it stays in the window
+	return []Notification{{Key: hashAlert("bz_flip " + peak.TimeTag), Rule: "bz_flip", Text: msg, Severity: severity, Time: observed}}
+}
@@ -0,0 +1,107 @@
+// cactus.go
+package main
+
+import (
+	"bufio"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const cactusURL = "https://www.sidc.be/cactus/out/cmecat.txt"
+
+// CME is one entry of the CACTus automated LASCO CME catalog
+type CME struct {
+	Number string
+	Onset  time.Time
+	Angle  float64 // principal angle, degrees
+	Width  float64 // angular width, degrees
+	Speed  float64 // median speed, km/s
+	Halo   string  // II partial halo, III or IV full halo; empty otherwise
+}
+
+func processCACTus(store StateStore) (Severity, error) {
+	var cmes []CME
+	err := fetchAndDecode("cactus", config.CACTusURL, func(body io.Reader) error {
+		var err error
+		cmes, err = parseCACTus(body)
+		return err
+	})
+	if err != nil {
+		log.Println("Error fetching CACTus CME catalog:", err)
+		return SeverityOK, err
+	}
+	if len(cmes) > 0 {
+		recordTimeTag("cactus", cmes[len(cmes)-1].Onset.Format(time.RFC3339))
+	}
+	notes := evaluateCACTus(cmes, time.Now().UTC())
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// parseCACTus reads the pipe-separated CME list
+// CME | t0 | dt0 | pa | da | v | dv | minv | maxv | halo?
+// stopping at the flow list that follows it
+func parseCACTus(r io.Reader) ([]CME, error) {
+	var cmes []CME
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.Contains(line, "Flow") {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), "#") {
+			continue
+		}
+		f := strings.Split(line, "|")
+		if len(f) < 10 {
+			continue
+		}
+		for i := range f {
+			f[i] = strings.TrimSpace(f[i])
+		}
+		onset, err := time.Parse("2006/01/02 15:04", f[1])
+		if err != nil {
+			continue
+		}
+		angle, _ := strconv.ParseFloat(f[3], 64)
+		width, _ := strconv.ParseFloat(f[4], 64)
+		speed, _ := strconv.ParseFloat(f[5], 64)
+		cmes = append(cmes, CME{Number: f[0], Onset: onset, Angle: angle, Width: width, Speed: speed, Halo: f[9]})
+	}
+	return cmes, scanner.Err()
+}
+
+// evaluateCACTus alerts on halo and partial-halo CMEs, the ones that may be
+// Earth-directed, detected in the last 48 hours at cme_min_speed or faster.
+// A full halo at 1000 km/s or more is critical.
+func evaluateCACTus(cmes []CME, now time.Time) []Notification {
+	var notes []Notification
+	for _, c := range cmes {
+		if c.Halo == "" || c.Speed < config.CMEMinSpeed || now.Sub(c.Onset) > 48*time.Hour {
+			continue
+		}
+		kind := "Partial halo"
+		severity := SeverityWarning
+		if c.Halo != "II" {
+			kind = "Halo"
+			if c.Speed >= 1000 {
+				severity = SeverityCritical
+			}
+		}
+		msg := renderMessage("cme", map[string]interface{}{
+			"Kind": kind, "Speed": c.Speed, "Width": c.Width, "Angle": c.Angle,
+			"Onset": c.Onset.Format("2006-01-02 15:04"),
+		})
+		notes = append(notes, Notification{
+			Key:      hashAlert("cactus " + c.Number + " " + c.Onset.Format(time.RFC3339)),
+			Rule:     "cme",
+			Text:     msg,
+			Severity: severity,
+			Time:     c.Onset,
+		})
+	}
+	return notes
+}
@@ -0,0 +1,98 @@
+// categories.go
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Alert categories channels and recipients can subscribe to
+const (
+	CategoryGeomagnetic  = "geomagnetic"
+	CategoryRadiation    = "radiation"
+	CategoryRadio        = "radio"
+	CategoryAurora       = "aurora"
+	CategorySystemHealth = "system-health"
+)
+
+var allCategories = []string{CategoryGeomagnetic, CategoryRadiation, CategoryRadio, CategoryAurora, CategorySystemHealth}
+
+var ruleCategories = map[string]string{
+	"kp":               CategoryGeomagnetic,
+	"bz":               CategoryGeomagnetic,
+	"bz_flip":          CategoryGeomagnetic,
+	"sudden_impulse":   CategoryGeomagnetic,
+	"recurrence":       CategoryGeomagnetic,
+	"cme":              CategoryGeomagnetic,
+	"flare_onset":      CategoryRadio,
+	"electron_fluence": CategoryRadiation,
+	"sep_hardness":     CategoryRadiation,
+	"bom_aurora":       CategoryAurora,
+	"test":             CategorySystemHealth,
+}
+
+// alertCategory tags a notification. Bulletins covering several effects go
+// by the NOAA scale letter (G, S or R) with the highest level.
+func alertCategory(n Notification) string {
+	if c, ok := ruleCategories[n.Rule]; ok {
+		return c
+	}
+	text := strings.ToLower(n.Text)
+	if n.Rule == "nict" {
+		switch {
+		case strings.Contains(text, "proton"):
+			return CategoryRadiation
+		case strings.Contains(text, "flare"):
+			return CategoryRadio
+		}
+		return CategoryGeomagnetic
+	}
+	best, level := CategoryGeomagnetic, 0
+	for _, m := range noaaScalePattern.FindAllStringSubmatch(n.Text, -1) {
+		if l := int(m[1][0] - '0'); l > level {
+			level = l
+			switch m[0][0] {
+			case 'S':
+				best = CategoryRadiation
+			case 'R':
+				best = CategoryRadio
+			default:
+				best = CategoryGeomagnetic
+			}
+		}
+	}
+	return best
+}
+
+// subscribed reports whether a channel or recipient with the given category
+// list wants category; an empty list means everything
+func subscribed(categories []string, category string) bool {
+	if len(categories) == 0 {
+		return true
+	}
+	for _, c := range categories {
+		if c == category {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSubscriptions reports the first unknown category in the config
+func checkSubscriptions() error {
+	lists := make(map[string][]string)
+	for channel, cats := range config.Subscriptions {
+		lists["subscriptions."+channel] = cats
+	}
+	for _, r := range config.SMSRecipients {
+		lists["sms_recipients "+r.To] = r.Categories
+	}
+	for where, cats := range lists {
+		for _, c := range cats {
+			if !subscribed(allCategories, c) {
+				return fmt.Errorf("%s: unknown category %q (want %s)", where, c, strings.Join(allCategories, ", "))
+			}
+		}
+	}
+	return nil
+}
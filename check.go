@@ -0,0 +1,121 @@
+// check.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	protonFluxURL = "https://services.swpc.noaa.gov/json/goes/primary/integral-protons-1-day.json"
+	xrayFluxURL   = "https://services.swpc.noaa.gov/json/goes/primary/xrays-1-day.json"
+)
+
+// runCheck behaves as a Nagios/Icinga plugin: one status line with perfdata
+// and the standard exit codes. Nothing is sent and no state is written.
+func runCheck(args []string) {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	protonWarning := fs.Float64("proton-warning", 10, "≥10 MeV proton flux (pfu) that is a WARNING; 10 is S1")
+	fs.Parse(args)
+
+	var result pollResult
+	var summary, perfdata []string
+	raise := func(s Severity) {
+		if s > result.Severity {
+			result.Severity = s
+		}
+	}
+
+	var alerts []Alert
+	if err := fetchJSON("swpc_alerts", swpcAlertsURL, &alerts); err != nil {
+		result.FetchFailed = true
+		summary = append(summary, "alerts unavailable")
+	} else {
+		notes := evaluateSWPCAlerts(alerts)
+		active := 0
+		for _, n := range notes {
+			if activeSeverity([]Notification{n}) > SeverityOK {
+				active++
+			}
+		}
+		raise(activeSeverity(notes))
+		summary = append(summary, fmt.Sprintf("%d active SWPC alert(s)", active))
+		perfdata = append(perfdata, fmt.Sprintf("swpc_alerts=%d", active))
+	}
+
+	kpThreshold, bzThreshold := thresholdsAt(time.Now())
+	if kpList, _, err := fetchMetric("kp"); err != nil {
+		result.FetchFailed = true
+		summary = append(summary, "Kp unavailable")
+	} else {
+		kp := kpList[len(kpList)-1].Value
+		raise(activeSeverity(evaluateKpIndex(kpList)))
+		summary = append(summary, fmt.Sprintf("Kp %.2f", kp))
+		perfdata = append(perfdata, perfValue("kp", kp, kpThreshold, config.KpCriticalThreshold))
+	}
+
+	if bzList, _, err := fetchMetric("bz"); err != nil {
+		result.FetchFailed = true
+		summary = append(summary, "Bz unavailable")
+	} else {
+		bz := bzList[len(bzList)-1].Value
+		raise(activeSeverity(append(evaluateBzField(bzList), evaluateBzFlip(bzList)...)))
+		summary = append(summary, fmt.Sprintf("Bz %.2f nT", bz))
+		// a "start:" range alerts when the value falls below start
+		perfdata = append(perfdata, fmt.Sprintf("bz=%.2f;%s:;%s:", bz,
+			formatPerf(bzThreshold), formatPerf(config.BzCriticalThreshold)))
+	}
+
+	type fluxCheck struct {
+		name, source, url, energy string
+		threshold                 float64
+	}
+	fluxChecks := []fluxCheck{
+		{"proton_flux", "goes_protons", protonFluxURL, ">=10 MeV", *protonWarning},
+		{"xray_flux", "goes_xrays", xrayFluxURL, "0.1-0.8nm", config.XrayFluxThreshold},
+	}
+	for _, f := range fluxChecks {
+		var readings []FluxReading
+		flux, ok := 0.0, false
+		if err := fetchJSON(f.source, f.url, &readings); err == nil {
+			flux, ok = latestFlux(readings, f.energy)
+		}
+		if !ok {
+			result.FetchFailed = true
+			summary = append(summary, f.name+" unavailable")
+			continue
+		}
+		if flux >= f.threshold {
+			raise(SeverityWarning)
+		}
+		summary = append(summary, fmt.Sprintf("%s %s", strings.Replace(f.name, "_", " ", 1), formatPerf(flux)))
+		perfdata = append(perfdata, fmt.Sprintf("%s=%s;%s", f.name, formatPerf(flux), formatPerf(f.threshold)))
+	}
+
+	state := map[int]string{exitOK: "OK", exitWarning: "WARNING", exitCritical: "CRITICAL", exitFetchFailed: "UNKNOWN"}
+	code := result.exitCode()
+	fmt.Printf("SWPC %s - %s | %s\n", state[code], strings.Join(summary, ", "), strings.Join(perfdata, " "))
+	os.Exit(code)
+}
+
+// latestFlux returns the newest reading for the given energy channel
+func latestFlux(readings []FluxReading, energy string) (float64, bool) {
+	for i := len(readings) - 1; i >= 0; i-- {
+		if readings[i].Energy == energy {
+			return readings[i].Flux, true
+		}
+	}
+	return 0, false
+}
+
+func perfValue(label string, value, warn, crit float64) string {
+	return fmt.Sprintf("%s=%.2f;%s;%s", label, value, formatPerf(warn), formatPerf(crit))
+}
+
+func formatPerf(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,78 @@
+// config_schema.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+func runConfigCommand(args []string) {
+	if len(args) == 1 && args[0] == "schema" {
+		out, _ := json.MarshalIndent(configSchema(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	fmt.Fprintln(os.Stderr, "usage: space_alerts config schema")
+	os.Exit(2)
+}
+
+// configSchema builds a JSON Schema for Config from its json, desc and enum
+// tags, using defaultConfig for default values
+func configSchema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Config{}), reflect.ValueOf(defaultConfig()))
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "Space weather alerts configuration"
+	return schema
+}
+
+func schemaFor(t reflect.Type, def reflect.Value) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice:
+		return map[string]interface{}{
+			"type":  "array",
+			"items": schemaFor(t.Elem(), reflect.Zero(t.Elem())),
+		}
+	case reflect.Map:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": schemaFor(t.Elem(), reflect.Zero(t.Elem())),
+		}
+	case reflect.Struct:
+		props := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			prop := schemaFor(f.Type, def.Field(i))
+			if desc := f.Tag.Get("desc"); desc != "" {
+				prop["description"] = desc
+			}
+			if enum := f.Tag.Get("enum"); enum != "" {
+				prop["enum"] = strings.Split(enum, ",")
+			}
+			if v := def.Field(i); !v.IsZero() {
+				prop["default"] = v.Interface()
+			}
+			props[name] = prop
+		}
+		return map[string]interface{}{
+			"type":                 "object",
+			"properties":           props,
+			"additionalProperties": false,
+		}
+	}
+	return map[string]interface{}{}
+}
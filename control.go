@@ -0,0 +1,137 @@
+// control.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ControlState holds remote pause/mute settings; a zero Until means the
+// setting lasts until resumed
+type ControlState struct {
+	Paused      bool      `json:"paused"`
+	PausedUntil time.Time `json:"paused_until"`
+	Muted       bool      `json:"muted"`
+	MutedUntil  time.Time `json:"muted_until"`
+}
+
+var (
+	controlMu sync.Mutex
+	control   ControlState
+)
+
+func (c ControlState) paused(now time.Time) bool {
+	return c.Paused && (c.PausedUntil.IsZero() || now.Before(c.PausedUntil))
+}
+
+func (c ControlState) muted(now time.Time) bool {
+	return c.Muted && (c.MutedUntil.IsZero() || now.Before(c.MutedUntil))
+}
+
+func currentControl() ControlState {
+	controlMu.Lock()
+	defer controlMu.Unlock()
+	return control
+}
+
+// applyControlCommand handles "pause [duration]", "mute [duration]",
+// "resume" (ends a pause), "unmute" and "test", saving a changed control
+// state straight away so it survives a restart
+func applyControlCommand(store StateStore, payload string) error {
+	fields := strings.Fields(strings.ToLower(payload))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty command")
+	}
+	var until time.Time
+	if len(fields) > 1 {
+		d, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return fmt.Errorf("bad duration %q: %v", fields[1], err)
+		}
+		until = time.Now().Add(d).UTC()
+	}
+
+	if fields[0] == "test" {
+		n := Notification{Rule: "test", Category: CategorySystemHealth, Text: "🚨 Test Alert: Space weather alert system is operational.", Time: time.Now().UTC()}
+		notifications.enqueue(n, routeNotification(n))
+		return nil
+	}
+
+	controlMu.Lock()
+	switch fields[0] {
+	case "pause":
+		control.Paused, control.PausedUntil = true, until
+	case "mute":
+		control.Muted, control.MutedUntil = true, until
+	case "resume":
+		control.Paused, control.PausedUntil = false, time.Time{}
+	case "unmute":
+		control.Muted, control.MutedUntil = false, time.Time{}
+	default:
+		controlMu.Unlock()
+		return fmt.Errorf("unknown command %q", fields[0])
+	}
+	state := control
+	controlMu.Unlock()
+
+	if err := store.Put("control", state); err != nil {
+		log.Println("Error saving control state:", err)
+	} else if err := store.Flush(); err != nil {
+		log.Println("Error saving state:", err)
+	}
+	return nil
+}
+
+// startControlListener subscribes to mqtt_control_topic and applies the
+// commands it receives, reconnecting with backoff
+func startControlListener(store StateStore) {
+	if config.MQTTBroker == "" {
+		return
+	}
+	go func() {
+		const initialBackoff = 5 * time.Second
+		backoff := initialBackoff
+		for {
+			connected, err := listenForControl(store)
+			if connected {
+				// the last connection worked, so start over rather than
+				// waiting as long as after repeated failures
+				backoff = initialBackoff
+			}
+			log.Printf("MQTT control connection lost: %v; retrying in %s", err, backoff)
+			time.Sleep(backoff)
+			if backoff < 5*time.Minute {
+				backoff *= 2
+			}
+		}
+	}()
+}
+
+// listenForControl applies commands until the connection drops, reporting
+// whether it got as far as subscribing
+func listenForControl(store StateStore) (bool, error) {
+	conn, err := dialMQTT(config.MQTTBroker, config.MQTTClientID, config.MQTTUsername, config.MQTTPassword)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if err := conn.Subscribe(config.MQTTControlTopic); err != nil {
+		return false, err
+	}
+	log.Println("Listening for control commands on", config.MQTTControlTopic)
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			return true, err
+		}
+		cmd := strings.TrimSpace(string(msg.Payload))
+		if err := applyControlCommand(store, cmd); err != nil {
+			log.Printf("Ignoring control command %q: %v", cmd, err)
+			continue
+		}
+		log.Printf("Applied control command %q", cmd)
+	}
+}
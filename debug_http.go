@@ -0,0 +1,73 @@
+// debug_http.go
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+const debugBodyLimit = 512
+
+// credential fields in JSON or form bodies, e.g. the BoM api_key, are
+// masked before a body is logged
+var (
+	jsonSecretPattern = regexp.MustCompile(`(?i)("(?:api_?key|password|secret|token|auth_token)"\s*:\s*)"[^"]*"?`)
+	formSecretPattern = regexp.MustCompile(`(?i)\b((?:api_?key|password|secret|token|auth_token)=)[^&\s]*`)
+)
+
+// debugTransport logs every HTTP exchange made through http.DefaultTransport,
+// which covers both the data feeds and the Twilio client
+type debugTransport struct {
+	next http.RoundTripper
+}
+
+func enableHTTPDebug() {
+	http.DefaultTransport = &debugTransport{next: http.DefaultTransport}
+	log.Println("HTTP debug tracing enabled")
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			head, _ := ioutil.ReadAll(io.LimitReader(body, debugBodyLimit+1))
+			body.Close()
+			log.Printf("[http] --> %s %s body=%s", req.Method, req.URL.Redacted(), truncateBody(head))
+		}
+	} else {
+		log.Printf("[http] --> %s %s", req.Method, req.URL.Redacted())
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+	if err != nil {
+		log.Printf("[http] <-- %s %s error after %s: %v", req.Method, req.URL.Redacted(), elapsed, err)
+		return nil, err
+	}
+
+	head, _ := ioutil.ReadAll(io.LimitReader(resp.Body, debugBodyLimit+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	log.Printf("[http] <-- %s %s %d in %s body=%s", req.Method, req.URL.Redacted(), resp.StatusCode, elapsed, truncateBody(head))
+	return resp, nil
+}
+
+func truncateBody(b []byte) string {
+	s, truncated := string(b), false
+	if len(b) > debugBodyLimit {
+		s, truncated = string(b[:debugBodyLimit]), true
+	}
+	s = jsonSecretPattern.ReplaceAllString(s, `${1}"[REDACTED]"`)
+	s = formSecretPattern.ReplaceAllString(s, "${1}[REDACTED]")
+	if truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
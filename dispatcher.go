@@ -0,0 +1,187 @@
+// dispatcher.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// dispatcher delivers notifications off the poll loop. Each channel has its
+// own queue and workers, so a hanging channel only backs up itself.
+type dispatcher struct {
+	queues map[string]chan Notification
+	wg     sync.WaitGroup
+
+	mu          sync.Mutex
+	health      map[string]*ChannelHealth
+	closed      bool           // queues closed: enqueue refuses notifications
+	abandoned   bool           // shutting down: stop sending what is still queued
+	undelivered []Notification // failed or abandoned, awaiting releaseUndelivered
+}
+
+// ChannelHealth is the delivery record of one notification channel
+type ChannelHealth struct {
+	Sent        int       `json:"sent"`
+	Failed      int       `json:"failed"`
+	Dropped     int       `json:"dropped"`
+	Queued      int       `json:"queued"`
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastFailed  bool      `json:"last_failed"`
+}
+
+var notifications *dispatcher
+
+func startDispatcher(notifiers []Notifier) *dispatcher {
+	d := &dispatcher{queues: make(map[string]chan Notification), health: make(map[string]*ChannelHealth)}
+	for _, ch := range notifiers {
+		queue := make(chan Notification, config.NotifyQueueSize)
+		d.queues[ch.Name()] = queue
+		d.health[ch.Name()] = &ChannelHealth{}
+		for i := 0; i < config.NotifyWorkers; i++ {
+			d.wg.Add(1)
+			go func(ch Notifier) {
+				defer d.wg.Done()
+				d.dispatchWorker(ch, queue)
+			}(ch)
+		}
+	}
+	return d
+}
+
+// enqueue hands n to each channel without blocking and returns the
+// channels that took it; a full queue drops it and counts the drop. Once
+// drain has begun nothing is queued, as the monitor is shutting down.
+func (d *dispatcher) enqueue(n Notification, channels []Notifier) []Notifier {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		log.Printf("Shutting down, not queueing %s notification", n.Rule)
+		return nil
+	}
+	var queued []Notifier
+	for _, ch := range channels {
+		select {
+		case d.queues[ch.Name()] <- n:
+			queued = append(queued, ch)
+		default:
+			log.Printf("%s queue full, dropping %s notification", ch.Name(), n.Rule)
+			writeEvent(eventError, eventIDDeliveryError, fmt.Sprintf("%s queue full, dropped %s notification", ch.Name(), n.Rule))
+			d.health[ch.Name()].Dropped++
+		}
+	}
+	return queued
+}
+
+// drain stops accepting notifications and sends what is queued, giving up
+// after timeout; whatever is left over is kept for releaseUndelivered
+func (d *dispatcher) drain(timeout time.Duration) {
+	d.mu.Lock()
+	d.closed = true
+	for _, queue := range d.queues {
+		close(queue)
+	}
+	d.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return
+	case <-time.After(timeout):
+	}
+	d.mu.Lock()
+	d.abandoned = true
+	d.mu.Unlock()
+	// the workers empty their queues without sending; a send already in
+	// flight still has up to notify_timeout_seconds
+	<-done
+}
+
+// releaseUndelivered gives back the claims of notifications that failed or
+// were abandoned at shutdown, so the next poll or run sends them again
+func (d *dispatcher) releaseUndelivered(store StateStore) {
+	d.mu.Lock()
+	undelivered := d.undelivered
+	d.undelivered = nil
+	d.mu.Unlock()
+	for _, n := range undelivered {
+		if n.Key == "" {
+			continue
+		}
+		store.Release(n.Key)
+		if n.OldKey != "" {
+			store.Release(n.OldKey)
+		}
+	}
+	if len(undelivered) > 0 {
+		log.Printf("%d undelivered notification(s) will be retried", len(undelivered))
+	}
+}
+
+func (d *dispatcher) dispatchWorker(ch Notifier, queue chan Notification) {
+	timeout := time.Duration(config.NotifyTimeout) * time.Second
+	for n := range queue {
+		d.mu.Lock()
+		abandoned := d.abandoned
+		if abandoned {
+			d.undelivered = append(d.undelivered, n)
+		}
+		d.mu.Unlock()
+		if abandoned {
+			continue
+		}
+		err := sendWithTimeout(ch, n, timeout)
+		d.recordDelivery(ch.Name(), n, err)
+		if err != nil {
+			log.Printf("%s notification failed: %v", ch.Name(), err)
+			writeEvent(eventError, eventIDDeliveryError, fmt.Sprintf("%s notification failed: %v", ch.Name(), err))
+		}
+	}
+}
+
+func (d *dispatcher) recordDelivery(channel string, n Notification, err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	h := d.health[channel]
+	if err != nil {
+		d.undelivered = append(d.undelivered, n)
+		h.Failed++
+		h.LastError = err.Error()
+		h.LastFailed = true
+		return
+	}
+	h.Sent++
+	h.LastSuccess = time.Now().UTC()
+	h.LastFailed = false
+}
+
+// channelHealth returns a copy of each channel's delivery record
+func (d *dispatcher) channelHealth() map[string]ChannelHealth {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	out := make(map[string]ChannelHealth, len(d.health))
+	for name, h := range d.health {
+		c := *h
+		c.Queued = len(d.queues[name])
+		out[name] = c
+	}
+	return out
+}
+
+// sendWithTimeout stops waiting after timeout; a stuck Send keeps running
+// in the background but no longer occupies the worker
+func sendWithTimeout(ch Notifier, n Notification, timeout time.Duration) error {
+	done := make(chan error, 1)
+	go func() { done <- ch.Send(n) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s", timeout)
+	}
+}
@@ -0,0 +1,132 @@
+// doctor.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+// DoctorCheck is the outcome of one diagnostic
+type DoctorCheck struct {
+	Name   string `json:"name"`
+	OK     bool   `json:"ok"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// runDoctor checks configuration, state backend and data feeds, exiting 1
+// if anything failed
+func runDoctor(args []string) {
+	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
+	format := formatFlag(fs)
+	fs.Parse(args)
+
+	checks := doctorChecks()
+	failed := false
+	for _, c := range checks {
+		failed = failed || !c.OK
+	}
+
+	if *format == "json" {
+		printJSON(checks)
+	} else {
+		for _, c := range checks {
+			mark := "OK  "
+			if !c.OK {
+				mark = "FAIL"
+			}
+			if c.Detail != "" {
+				fmt.Printf("[%s] %s: %s\n", mark, c.Name, c.Detail)
+			} else {
+				fmt.Printf("[%s] %s\n", mark, c.Name)
+			}
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func doctorChecks() []DoctorCheck {
+	var checks []DoctorCheck
+	check := func(name string, err error) {
+		c := DoctorCheck{Name: name, OK: err == nil}
+		if err != nil {
+			c.Detail = err.Error()
+		}
+		checks = append(checks, c)
+	}
+
+	var twilioErr error
+	if !config.DryRun && (config.TwilioSID == "" || config.TwilioAuth == "" || config.TwilioFrom == "" || config.TwilioTo == "") {
+		twilioErr = fmt.Errorf("twilio_sid, twilio_auth, twilio_from and twilio_to are required unless dry_run is set")
+	}
+	check("twilio config", twilioErr)
+
+	var intervalErr error
+	if config.CheckInterval <= 0 {
+		intervalErr = fmt.Errorf("check_interval_minutes must be positive, got %d", config.CheckInterval)
+	}
+	check("check interval", intervalErr)
+	check("threshold schedules", checkThresholdSchedules())
+	check("subscriptions", checkSubscriptions())
+	check("source strategy", checkSourceStrategies())
+
+	for rule := range config.Templates {
+		_, err := executeTemplate(config.Templates[rule], templateSamples[rule])
+		check("template "+rule, err)
+	}
+
+	var snap HealthSnapshot
+	check("state backend ("+config.StateBackend+")", newStateStore().Get("source_health", &snap))
+
+	var alerts []Alert
+	check("feed swpc_alerts", fetchJSON("swpc_alerts", swpcAlertsURL, &alerts))
+	if config.SEPHardFlux > 0 {
+		var readings []FluxReading
+		check("feed goes_protons", fetchJSON("goes_protons", protonFluxURL, &readings))
+	}
+	if config.FluenceThreshold > 0 {
+		var readings []FluxReading
+		check("feed goes_electrons", fetchJSON("goes_electrons", electronFluxURL, &readings))
+	}
+	if config.MetOffice {
+		_, err := fetchMetOffice()
+		check("feed metoffice", err)
+	}
+	if config.CACTus {
+		check("feed cactus", fetchAndDecode("cactus", config.CACTusURL, func(body io.Reader) error {
+			_, err := parseCACTus(body)
+			return err
+		}))
+	}
+	if config.NICT {
+		check("feed nict", fetchAndDecode("nict", config.NICTURL, func(io.Reader) error { return nil }))
+	}
+	if config.BoMAPIKey != "" {
+		var alerts []bomAuroraNotice
+		check("feed bom_aurora", bomPost("bom_aurora", "get-aurora-alert", nil, &alerts))
+	}
+	if config.IMAPServer != "" {
+		check("imap "+config.IMAPServer, checkIMAP())
+	}
+	metrics := make([]string, 0, len(config.Sources))
+	for metric := range config.Sources {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	for _, metric := range metrics {
+		for _, name := range config.Sources[metric] {
+			p, ok := lookupProvider(name)
+			if !ok || p.Metric != metric {
+				check("feed "+name, fmt.Errorf("not a known %s provider", metric))
+				continue
+			}
+			_, err := p.Fetch()
+			check("feed "+name+" ("+metric+")", err)
+		}
+	}
+	return checks
+}
@@ -0,0 +1,95 @@
+// electron_fluence.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	electronFluxURL = "https://services.swpc.noaa.gov/json/goes/primary/integral-electrons-1-day.json"
+	// electronSampleSeconds is the cadence of the GOES integral electron
+	// averages each flux is multiplied by
+	electronSampleSeconds = 300
+)
+
+// ElectronFluence is the running >2 MeV electron fluence for one UTC day,
+// kept in the electron_fluence state document between polls
+type ElectronFluence struct {
+	Day     string    `json:"day"`
+	Fluence float64   `json:"fluence"` // electrons/cm²/sr
+	Through time.Time `json:"through"` // newest sample counted
+}
+
+// processElectronFluence adds the >2 MeV electron samples since the last
+// poll to today's fluence, a measure of spacecraft internal charging risk
+func processElectronFluence(store StateStore) (Severity, error) {
+	var readings []FluxReading
+	err := fetchJSON("goes_electrons", electronFluxURL, &readings)
+	series := fluxSeries(readings, ">=2 MeV")
+	if err == nil && len(series) == 0 {
+		err = fmt.Errorf("no >=2 MeV electron readings")
+	}
+	if err != nil {
+		log.Println("Error fetching electron flux:", err)
+		return SeverityOK, err
+	}
+	latest := series[len(series)-1]
+	recordTimeTag("goes_electrons", latest.TimeTag)
+
+	var fluence ElectronFluence
+	if err := store.Get("electron_fluence", &fluence); err != nil {
+		log.Println("Error reading electron fluence:", err)
+		return SeverityOK, err
+	}
+	fluence = accumulateFluence(fluence, series)
+	if err := store.Put("electron_fluence", fluence); err != nil {
+		log.Println("Error saving electron fluence:", err)
+	}
+	recordReading("electron_fluence", "goes_electrons", fluence.Fluence, latest.TimeTag)
+	notes := evaluateElectronFluence(fluence, latest.Value)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// accumulateFluence adds samples newer than those already counted, starting
+// afresh at each UTC midnight. The 1-day product means a first run, or one
+// after an outage, still covers the whole day so far.
+func accumulateFluence(fluence ElectronFluence, series []Sample) ElectronFluence {
+	for _, s := range series {
+		t, err := parseTimeTag(s.TimeTag)
+		if err != nil || !t.After(fluence.Through) {
+			continue
+		}
+		if day := t.UTC().Format("2006-01-02"); day != fluence.Day {
+			fluence = ElectronFluence{Day: day}
+		}
+		fluence.Fluence += s.Value * electronSampleSeconds
+		fluence.Through = t
+	}
+	return fluence
+}
+
+// evaluateElectronFluence alerts once per day and level as the fluence
+// crosses electron_fluence_threshold and the critical threshold
+func evaluateElectronFluence(fluence ElectronFluence, flux float64) []Notification {
+	if config.FluenceThreshold <= 0 || fluence.Fluence < config.FluenceThreshold {
+		return nil
+	}
+	severity := SeverityWarning
+	if config.FluenceCritical > 0 && fluence.Fluence >= config.FluenceCritical {
+		severity = SeverityCritical
+	}
+	msg := renderMessage("electron_fluence", map[string]interface{}{
+		"Fluence": fluence.Fluence, "Flux": flux, "Day": fluence.Day,
+		"Through": fluence.Through.UTC().Format("15:04"),
+	})
+	return []Notification{{
+		Key:      hashAlert("electron_fluence " + fluence.Day + " " + severity.String()),
+		Rule:     "electron_fluence",
+		Text:     msg,
+		Severity: severity,
+		Time:     fluence.Through,
+	}}
+}
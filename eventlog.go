@@ -0,0 +1,36 @@
+// eventlog.go
+package main
+
+// Windows Event Log IDs
+const (
+	eventIDStarted       = 100
+	eventIDAlert         = 1000
+	eventIDFetchError    = 2000
+	eventIDDeliveryError = 2001
+)
+
+// Event Log levels
+const (
+	eventInfo = iota
+	eventWarning
+	eventError
+)
+
+// eventLogNotifier writes alerts to the Windows Event Log, with the level
+// following the alert severity
+type eventLogNotifier struct{}
+
+func (eventLogNotifier) Name() string { return "eventlog" }
+
+func (eventLogNotifier) Render(n Notification) string { return n.Text }
+
+func (e eventLogNotifier) Send(n Notification) error {
+	level := eventInfo
+	switch n.Severity {
+	case SeverityWarning:
+		level = eventWarning
+	case SeverityCritical:
+		level = eventError
+	}
+	return writeEvent(level, eventIDAlert, e.Render(n))
+}
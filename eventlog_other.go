@@ -0,0 +1,20 @@
+// eventlog_other.go
+//go:build !windows
+
+package main
+
+import "log"
+
+func openEventLog() {
+	if config.WindowsEventLog {
+		log.Println("windows_event_log is only supported on Windows; ignoring")
+	}
+}
+
+func eventLogAvailable() bool {
+	return false
+}
+
+func writeEvent(level int, id uint32, msg string) error {
+	return nil
+}
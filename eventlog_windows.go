@@ -0,0 +1,46 @@
+// eventlog_windows.go
+//go:build windows
+
+package main
+
+import (
+	"log"
+
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+var winlog *eventlog.Log
+
+// openEventLog registers and opens the event source when windows_event_log
+// is set. Registration needs admin rights and fails if the source already
+// exists, so its error is ignored and opening decides.
+func openEventLog() {
+	if !config.WindowsEventLog {
+		return
+	}
+	_ = eventlog.InstallAsEventCreate(config.WindowsEventSource, eventlog.Error|eventlog.Warning|eventlog.Info)
+	l, err := eventlog.Open(config.WindowsEventSource)
+	if err != nil {
+		log.Println("Error opening Windows Event Log:", err)
+		return
+	}
+	winlog = l
+	writeEvent(eventInfo, eventIDStarted, "Space weather alert monitor started")
+}
+
+func eventLogAvailable() bool {
+	return winlog != nil
+}
+
+func writeEvent(level int, id uint32, msg string) error {
+	if winlog == nil {
+		return nil
+	}
+	switch level {
+	case eventError:
+		return winlog.Error(id, msg)
+	case eventWarning:
+		return winlog.Warning(id, msg)
+	}
+	return winlog.Info(id, msg)
+}
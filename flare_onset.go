@@ -0,0 +1,129 @@
+// flare_onset.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"time"
+)
+
+const xrays6HourURL = "https://services.swpc.noaa.gov/json/goes/primary/xrays-6-hour.json"
+
+// processFlareOnset watches the 1-minute GOES long-channel X-ray flux for a
+// flare that is starting now, rather than waiting for SWPC's bulletin
+func processFlareOnset(store StateStore) (Severity, error) {
+	var readings []FluxReading
+	err := fetchJSON("goes_xrays", xrays6HourURL, &readings)
+	series := fluxSeries(readings, "0.1-0.8nm")
+	if err == nil && len(series) == 0 {
+		err = fmt.Errorf("no 0.1-0.8nm X-ray readings")
+	}
+	if err != nil {
+		log.Println("Error fetching X-ray flux:", err)
+		return SeverityOK, err
+	}
+	latest := series[len(series)-1]
+	recordTimeTag("goes_xrays", latest.TimeTag)
+	recordReading("xray_flux", "goes_xrays", latest.Value, latest.TimeTag)
+	notes := evaluateFlareOnset(series)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// fluxSeries picks one energy channel out of a GOES product
+func fluxSeries(readings []FluxReading, energy string) []Sample {
+	var samples []Sample
+	for _, r := range readings {
+		if r.Energy == energy && r.Flux > 0 {
+			samples = append(samples, Sample{TimeTag: r.TimeTag, Value: r.Flux})
+		}
+	}
+	return samples
+}
+
+// evaluateFlareOnset applies the NOAA flare start criterion to every window
+// ending since the previous poll, with some overlap so a flare that rose and
+// peaked between polls is still reported: four consecutive one-minute rises
+// ending at least 40% above where they began, with the flux at or above
+// flare_onset_flux. X-class is critical.
+func evaluateFlareOnset(series []Sample) []Notification {
+	const rises = 4
+	if config.FlareOnsetFlux <= 0 || len(series) <= rises {
+		return nil
+	}
+	newest, err := parseTimeTag(series[len(series)-1].TimeTag)
+	if err != nil {
+		return nil
+	}
+	since := newest.Add(-time.Duration(config.CheckInterval+10) * time.Minute)
+	var notes []Notification
+	reported := make(map[string]bool)
+	for end := rises; end < len(series); end++ {
+		at, err := parseTimeTag(series[end].TimeTag)
+		if err != nil || at.Before(since) {
+			continue
+		}
+		began, ok := flareOnsetAt(series, end, rises)
+		if !ok || reported[began] {
+			continue
+		}
+		reported[began] = true
+		latest := series[end]
+		severity := SeverityWarning
+		if latest.Value >= 1e-4 {
+			severity = SeverityCritical
+		}
+		msg := renderMessage("flare_onset", map[string]interface{}{
+			"Class": flareClass(latest.Value), "Flux": latest.Value, "TimeTag": latest.TimeTag,
+		})
+		notes = append(notes, Notification{
+			Key:      hashAlert("flare_onset " + began),
+			Rule:     "flare_onset",
+			Text:     msg,
+			Severity: severity,
+			Time:     at,
+		})
+	}
+	return notes
+}
+
+// flareOnsetAt reports whether the rises samples up to series[end] meet the
+// start criterion and, if so, when the rise began, which stays put while
+// the flare grows so one flare is one alert
+func flareOnsetAt(series []Sample, end, rises int) (string, bool) {
+	run := series[end-rises : end+1]
+	for i := 1; i < len(run); i++ {
+		if run[i].Value <= run[i-1].Value {
+			return "", false
+		}
+	}
+	start, latest := run[0], run[len(run)-1]
+	if latest.Value < 1.4*start.Value || latest.Value < config.FlareOnsetFlux {
+		return "", false
+	}
+	began, err := parseTimeTag(start.TimeTag)
+	if err != nil {
+		return "", false
+	}
+	for i := end - rises - 1; i >= 0 && series[i].Value < series[i+1].Value; i-- {
+		if t, err := parseTimeTag(series[i].TimeTag); err == nil {
+			began = t
+		}
+	}
+	return began.Format("2006-01-02T15:04"), true
+}
+
+// flareClass converts a 0.1-0.8nm flux in W/m² to a class like M2.3
+func flareClass(flux float64) string {
+	classes := []struct {
+		letter string
+		base   float64
+	}{{"X", 1e-4}, {"M", 1e-5}, {"C", 1e-6}, {"B", 1e-7}, {"A", 1e-8}}
+	for _, c := range classes {
+		if flux >= c.base {
+			return fmt.Sprintf("%s%.1f", c.letter, math.Floor(flux/c.base*10)/10)
+		}
+	}
+	return "A0.0"
+}
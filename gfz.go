@@ -0,0 +1,63 @@
+// gfz.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const gfzKpURL = "https://kp.gfz-potsdam.de/app/json/"
+
+// gfzSeries returns a fetcher for the last 24 h of a GFZ Potsdam index: Kp,
+// the official 3-hourly index, or Hp30, its open-ended half-hourly sibling on
+// the same scale. GFZ stamps each value at the start of its interval; the
+// samples are stamped at the end, when the value is complete, so freshness
+// checks see how old the data really is.
+func gfzSeries(source, index string, interval time.Duration) func() ([]Sample, error) {
+	return func() ([]Sample, error) {
+		now := time.Now().UTC()
+		q := url.Values{
+			"start": {now.Add(-24 * time.Hour).Format("2006-01-02T15:04:05Z")},
+			"end":   {now.Format("2006-01-02T15:04:05Z")},
+			"index": {index},
+		}
+		req, err := http.NewRequest("GET", gfzKpURL+"?"+q.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var resp map[string]json.RawMessage
+		err = fetchRequest(source, req, func(body io.Reader) error {
+			return json.NewDecoder(body).Decode(&resp)
+		})
+		if err != nil {
+			return nil, err
+		}
+		var times []string
+		var values []*float64
+		if err := json.Unmarshal(resp["datetime"], &times); err != nil {
+			return nil, fmt.Errorf("datetime: %v", err)
+		}
+		if err := json.Unmarshal(resp[index], &values); err != nil {
+			return nil, fmt.Errorf("%s: %v", index, err)
+		}
+		if len(times) != len(values) {
+			return nil, fmt.Errorf("%d times but %d %s values", len(times), len(values), index)
+		}
+		var samples []Sample
+		for i, v := range values {
+			start, err := parseTimeTag(times[i])
+			if v == nil || err != nil {
+				continue
+			}
+			samples = append(samples, Sample{TimeTag: start.Add(interval).Format(time.RFC3339), Value: *v})
+		}
+		if len(samples) > 0 {
+			recordTimeTag(source, samples[len(samples)-1].TimeTag)
+		}
+		return samples, nil
+	}
+}
@@ -0,0 +1,136 @@
+// ha_api.go
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const ovationURL = "https://services.swpc.noaa.gov/json/ovation_aurora_latest.json"
+
+// registerSensorAPI adds the Home Assistant RESTful sensor endpoints. They
+// need http_token, sent as "Authorization: Bearer <token>".
+func registerSensorAPI(mux *http.ServeMux) {
+	mux.HandleFunc("/api/kp", requireToken(handleKpSensor))
+	mux.HandleFunc("/api/storm", requireToken(handleStormSensor))
+	mux.HandleFunc("/api/aurora", requireToken(handleAuroraSensor))
+}
+
+func requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		want := "Bearer " + config.HTTPToken
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte(want)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+func writeSensor(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func handleKpSensor(w http.ResponseWriter, r *http.Request) {
+	kp, ok := currentReadings()["kp"]
+	if !ok {
+		http.Error(w, "no Kp reading yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeSensor(w, map[string]interface{}{"kp": kp.Value, "time": kp.Time, "source": kp.Source})
+}
+
+var stormNames = []string{"none", "minor", "moderate", "strong", "severe", "extreme"}
+
+// geomagneticStormLevel maps Kp onto the NOAA G scale (Kp 5 = G1 ... Kp 9 = G5)
+func geomagneticStormLevel(kp float64) int {
+	level := int(math.Floor(kp+0.01)) - 4
+	if level < 0 {
+		return 0
+	}
+	if level > 5 {
+		return 5
+	}
+	return level
+}
+
+func handleStormSensor(w http.ResponseWriter, r *http.Request) {
+	kp, ok := currentReadings()["kp"]
+	if !ok {
+		http.Error(w, "no Kp reading yet", http.StatusServiceUnavailable)
+		return
+	}
+	level := geomagneticStormLevel(kp.Value)
+	writeSensor(w, map[string]interface{}{
+		"level":       fmt.Sprintf("G%d", level),
+		"scale":       level,
+		"description": stormNames[level],
+		"kp":          kp.Value,
+		"time":        kp.Time,
+	})
+}
+
+// OvationForecast is SWPC's aurora nowcast grid of [longitude, latitude, %]
+type OvationForecast struct {
+	ObservationTime string       `json:"Observation Time"`
+	ForecastTime    string       `json:"Forecast Time"`
+	Coordinates     [][3]float64 `json:"coordinates"`
+}
+
+var (
+	ovationMu      sync.Mutex
+	ovationCache   OvationForecast
+	ovationFetched time.Time
+)
+
+func latestOvation() (OvationForecast, error) {
+	ovationMu.Lock()
+	defer ovationMu.Unlock()
+	if time.Since(ovationFetched) < 5*time.Minute {
+		return ovationCache, nil
+	}
+	var f OvationForecast
+	if err := fetchJSON("ovation_aurora", ovationURL, &f); err != nil {
+		return f, err
+	}
+	recordTimeTag("ovation_aurora", f.ObservationTime)
+	ovationCache, ovationFetched = f, time.Now()
+	return f, nil
+}
+
+// auroraProbability returns the grid value nearest to lat/lon
+func auroraProbability(f OvationForecast, lat, lon float64) float64 {
+	lon = math.Mod(lon+360, 360)
+	best, bestDist := 0.0, math.Inf(1)
+	for _, c := range f.Coordinates {
+		dLon := math.Abs(c[0] - lon)
+		if dLon > 180 {
+			dLon = 360 - dLon
+		}
+		if d := dLon*dLon + (c[1]-lat)*(c[1]-lat); d < bestDist {
+			best, bestDist = c[2], d
+		}
+	}
+	return best
+}
+
+func handleAuroraSensor(w http.ResponseWriter, r *http.Request) {
+	f, err := latestOvation()
+	if err != nil {
+		http.Error(w, "aurora forecast unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	writeSensor(w, map[string]interface{}{
+		"probability":      auroraProbability(f, config.Latitude, config.Longitude),
+		"latitude":         config.Latitude,
+		"longitude":        config.Longitude,
+		"observation_time": f.ObservationTime,
+		"forecast_time":    f.ForecastTime,
+	})
+}
@@ -0,0 +1,129 @@
+// health.go
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	loopMu       sync.Mutex
+	lastLoop     time.Time
+	lastPollDone time.Time
+)
+
+// markLoop notes that the poll loop is alive; polled reports a finished poll
+func markLoop(polled bool) {
+	loopMu.Lock()
+	defer loopMu.Unlock()
+	lastLoop = time.Now().UTC()
+	if polled {
+		lastPollDone = lastLoop
+	}
+}
+
+// onDemandSources are fetched only when a client asks, not every poll, so
+// their age says nothing about readiness
+var onDemandSources = map[string]bool{"ovation_aurora": true}
+
+// HealthReport is the body of /healthz and /readyz
+type HealthReport struct {
+	Status    string                   `json:"status"`
+	Problems  []string                 `json:"problems,omitempty"`
+	LastLoop  time.Time                `json:"last_loop"`
+	LastPoll  time.Time                `json:"last_poll"`
+	Paused    bool                     `json:"paused"`
+	Sources   map[string]time.Time     `json:"sources"`
+	Notifiers map[string]ChannelHealth `json:"notifiers"`
+}
+
+// healthReport checks liveness: the loop has come round within two poll
+// intervals. For readiness it also wants a completed poll, every source (or
+// for failover metrics, one of its providers) to have succeeded within three
+// intervals and no channel whose last delivery failed. Sources aren't
+// expected to be fresh while polling is paused.
+func healthReport(ready bool) HealthReport {
+	now := time.Now().UTC()
+	interval := time.Duration(config.CheckInterval) * time.Minute
+	loopMu.Lock()
+	report := HealthReport{LastLoop: lastLoop, LastPoll: lastPollDone}
+	loopMu.Unlock()
+	report.Paused = currentControl().paused(now)
+	report.Sources = make(map[string]time.Time)
+	snap := healthSnapshot()
+	for _, name := range sortedSources(snap) {
+		report.Sources[name] = snap.Sources[name].LastSuccess
+	}
+	if notifications != nil {
+		report.Notifiers = notifications.channelHealth()
+	}
+
+	if report.LastLoop.IsZero() || now.Sub(report.LastLoop) > 2*interval+time.Minute {
+		report.Problems = append(report.Problems, "poll loop is not running")
+	}
+	if ready {
+		if report.LastPoll.IsZero() && !report.Paused {
+			report.Problems = append(report.Problems, "no poll has completed yet")
+		}
+		if !report.Paused {
+			report.Problems = append(report.Problems, staleSources(report.Sources, snap, now, interval)...)
+		}
+		for name, h := range report.Notifiers {
+			if h.LastFailed {
+				report.Problems = append(report.Problems, "notifier "+name+" failed: "+h.LastError)
+			}
+		}
+	}
+	report.Status = "ok"
+	if len(report.Problems) > 0 {
+		report.Status = "fail"
+	}
+	return report
+}
+
+// staleSources lists the polled sources, or for failover metrics the
+// metrics, that have not succeeded within three intervals
+func staleSources(sources map[string]time.Time, snap HealthSnapshot, now time.Time, interval time.Duration) []string {
+	var problems []string
+	// failover providers count per metric: one working provider is enough
+	metricOK := make(map[string]bool)
+	providerMetric := make(map[string]string)
+	for metric, names := range config.Sources {
+		for _, name := range names {
+			providerMetric[name] = metric
+			if t, ok := sources[name]; ok && now.Sub(t) <= 3*interval {
+				metricOK[metric] = true
+			}
+		}
+	}
+	for _, name := range sortedSources(snap) {
+		if metric, ok := providerMetric[name]; ok {
+			if !metricOK[metric] {
+				problems = append(problems, "no "+metric+" source has succeeded recently")
+				metricOK[metric] = true // report once
+			}
+			continue
+		}
+		if !onDemandSources[name] && now.Sub(sources[name]) > 3*interval {
+			problems = append(problems, "source "+name+" has not succeeded recently")
+		}
+	}
+	return problems
+}
+
+func registerHealthEndpoints(mux *http.ServeMux) {
+	serve := func(ready bool) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			report := healthReport(ready)
+			w.Header().Set("Content-Type", "application/json")
+			if report.Status != "ok" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+			json.NewEncoder(w).Encode(report)
+		}
+	}
+	mux.HandleFunc("/healthz", serve(false))
+	mux.HandleFunc("/readyz", serve(true))
+}
@@ -0,0 +1,73 @@
+// history.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+)
+
+const historyLimit = 200
+
+// HistoryEntry records a notification handed to the dispatcher
+type HistoryEntry struct {
+	Time     time.Time `json:"time"`
+	Rule     string    `json:"rule"`
+	Category string    `json:"category,omitempty"`
+	Channels []string  `json:"channels"`
+	Text     string    `json:"text"`
+}
+
+func recordHistory(store StateStore, n Notification, channels []Notifier) {
+	var history []HistoryEntry
+	if err := store.Get("history", &history); err != nil {
+		log.Println("Error reading alert history:", err)
+	}
+	entry := HistoryEntry{Time: time.Now().UTC(), Rule: n.Rule, Category: n.Category, Text: n.Text}
+	for _, ch := range channels {
+		entry.Channels = append(entry.Channels, ch.Name())
+	}
+	history = append(history, entry)
+	if len(history) > historyLimit {
+		history = history[len(history)-historyLimit:]
+	}
+	if err := store.Put("history", history); err != nil {
+		log.Println("Error saving alert history:", err)
+	}
+}
+
+// runHistory lists recently sent alerts, newest first
+func runHistory(args []string) {
+	fs := flag.NewFlagSet("history", flag.ExitOnError)
+	format := formatFlag(fs)
+	limit := fs.Int("n", 20, "number of entries to show")
+	fs.Parse(args)
+
+	var history []HistoryEntry
+	if err := newStateStore().Get("history", &history); err != nil {
+		log.Fatalf("Failed to read history: %v", err)
+	}
+	if *limit > 0 && len(history) > *limit {
+		history = history[len(history)-*limit:]
+	}
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+
+	if *format == "json" {
+		if history == nil {
+			history = []HistoryEntry{}
+		}
+		printJSON(history)
+		return
+	}
+	if len(history) == 0 {
+		fmt.Println("No alerts sent yet.")
+		return
+	}
+	for _, e := range history {
+		fmt.Printf("%s  %s -> %v\n", e.Time.Format(time.RFC3339), e.Rule, e.Channels)
+		fmt.Println(indent(e.Text, "    "))
+	}
+}
@@ -0,0 +1,27 @@
+// http_server.go
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// startHTTPServer serves monitoring endpoints when http_listen is set
+func startHTTPServer() {
+	if config.HTTPListen == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		writePrometheusMetrics(w)
+	})
+	registerHealthEndpoints(mux)
+	if config.HTTPToken != "" {
+		registerSensorAPI(mux)
+	}
+	go func() {
+		log.Println("HTTP server listening on", config.HTTPListen)
+		log.Println("HTTP server stopped:", http.ListenAndServe(config.HTTPListen, mux))
+	}()
+}
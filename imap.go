@@ -0,0 +1,153 @@
+// imap.go
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// imapConn is a minimal IMAP4rev1 client over TLS: enough to log in, search
+// a mailbox, fetch whole messages and flag them as seen
+type imapConn struct {
+	conn net.Conn
+	rd   *bufio.Reader
+	tag  int
+}
+
+// imapResponse is one untagged response line with any literals it carried
+type imapResponse struct {
+	Line     string
+	Literals [][]byte
+}
+
+func dialIMAP(addr string) (*imapConn, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, addr = addr, addr+":993"
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 15 * time.Second}, "tcp", addr, &tls.Config{ServerName: host})
+	if err != nil {
+		return nil, err
+	}
+	c := &imapConn{conn: conn, rd: bufio.NewReader(conn)}
+	conn.SetDeadline(time.Now().Add(time.Minute))
+	greeting, err := c.rd.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") {
+		conn.Close()
+		return nil, fmt.Errorf("unexpected IMAP greeting %q", strings.TrimSpace(greeting))
+	}
+	return c, nil
+}
+
+// command sends one command and returns its untagged responses, failing
+// unless the tagged status is OK
+func (c *imapConn) command(format string, args ...interface{}) ([]imapResponse, error) {
+	c.tag++
+	tag := fmt.Sprintf("a%03d", c.tag)
+	c.conn.SetDeadline(time.Now().Add(time.Minute))
+	if _, err := fmt.Fprintf(c.conn, "%s %s\r\n", tag, fmt.Sprintf(format, args...)); err != nil {
+		return nil, err
+	}
+	var responses []imapResponse
+	for {
+		line, err := c.rd.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if strings.HasPrefix(line, tag+" ") {
+			status := strings.TrimPrefix(line, tag+" ")
+			if !strings.HasPrefix(status, "OK") {
+				return nil, fmt.Errorf("IMAP: %s", status)
+			}
+			return responses, nil
+		}
+		resp := imapResponse{Line: line}
+		// a line ending in {n} is followed by n bytes of literal data and
+		// then the rest of the response
+		for strings.HasSuffix(line, "}") {
+			open := strings.LastIndex(line, "{")
+			if open < 0 {
+				break
+			}
+			n, err := strconv.Atoi(line[open+1 : len(line)-1])
+			if err != nil {
+				break
+			}
+			literal := make([]byte, n)
+			if _, err := io.ReadFull(c.rd, literal); err != nil {
+				return nil, err
+			}
+			resp.Literals = append(resp.Literals, literal)
+			if line, err = c.rd.ReadString('\n'); err != nil {
+				return nil, err
+			}
+			line = strings.TrimRight(line, "\r\n")
+			resp.Line += " " + line
+		}
+		responses = append(responses, resp)
+	}
+}
+
+func (c *imapConn) Login(user, password string) error {
+	_, err := c.command("LOGIN %s %s", imapQuote(user), imapQuote(password))
+	return err
+}
+
+func (c *imapConn) Select(mailbox string) error {
+	_, err := c.command("SELECT %s", imapQuote(mailbox))
+	return err
+}
+
+// Search returns the UIDs matching an IMAP search expression
+func (c *imapConn) Search(criteria string) ([]string, error) {
+	responses, err := c.command("UID SEARCH %s", criteria)
+	if err != nil {
+		return nil, err
+	}
+	var uids []string
+	for _, r := range responses {
+		if strings.HasPrefix(r.Line, "* SEARCH") {
+			uids = append(uids, strings.Fields(strings.TrimPrefix(r.Line, "* SEARCH"))...)
+		}
+	}
+	return uids, nil
+}
+
+// Fetch returns the raw RFC 822 message without marking it seen
+func (c *imapConn) Fetch(uid string) ([]byte, error) {
+	responses, err := c.command("UID FETCH %s BODY.PEEK[]", uid)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range responses {
+		if len(r.Literals) > 0 {
+			return r.Literals[0], nil
+		}
+	}
+	return nil, fmt.Errorf("no body returned for message %s", uid)
+}
+
+func (c *imapConn) MarkSeen(uid string) error {
+	_, err := c.command(`UID STORE %s +FLAGS.SILENT (\Seen)`, uid)
+	return err
+}
+
+func (c *imapConn) Close() error {
+	c.command("LOGOUT")
+	return c.conn.Close()
+}
+
+func imapQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
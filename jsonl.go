@@ -0,0 +1,52 @@
+// jsonl.go
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	jsonlMu  sync.Mutex
+	jsonlOut *json.Encoder
+)
+
+// openJSONL starts the JSON Lines stream when jsonl_output is set: a file
+// path to append to, or "-" for stdout (logs go to stderr)
+func openJSONL() {
+	var w io.Writer
+	switch config.JSONLOutput {
+	case "":
+		return
+	case "-":
+		w = os.Stdout
+	default:
+		f, err := os.OpenFile(config.JSONLOutput, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			log.Fatalf("Failed to open jsonl_output: %v", err)
+		}
+		w = f
+	}
+	jsonlMu.Lock()
+	jsonlOut = json.NewEncoder(w)
+	jsonlMu.Unlock()
+}
+
+// emitJSONL writes one event line; fields are merged with the event type
+// and a timestamp
+func emitJSONL(event string, fields map[string]interface{}) {
+	jsonlMu.Lock()
+	defer jsonlMu.Unlock()
+	if jsonlOut == nil {
+		return
+	}
+	fields["event"] = event
+	fields["ts"] = time.Now().UTC()
+	if err := jsonlOut.Encode(fields); err != nil {
+		log.Println("Error writing jsonl event:", err)
+	}
+}
@@ -0,0 +1,154 @@
+// log_file.go
+package main
+
+import (
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// setupLogging tees log output to log_file when configured
+func setupLogging() {
+	if config.LogFile == "" {
+		return
+	}
+	w := &rotatingWriter{
+		path:       config.LogFile,
+		maxSize:    int64(config.LogMaxSizeMB) * 1024 * 1024,
+		maxFileAge: time.Duration(config.LogRotateHours) * time.Hour,
+		maxBackups: config.LogMaxBackups,
+		maxAge:     time.Duration(config.LogMaxAgeDays) * 24 * time.Hour,
+	}
+	if err := w.open(); err != nil {
+		log.Fatalf("Failed to open log file: %v", err)
+	}
+	log.SetOutput(io.MultiWriter(os.Stderr, w))
+}
+
+// rotatingWriter appends to a file, moving it aside when it grows past
+// maxSize or gets older than maxFileAge, and prunes old rotated files
+type rotatingWriter struct {
+	path       string
+	maxSize    int64
+	maxFileAge time.Duration
+	maxBackups int
+	maxAge     time.Duration
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+func (w *rotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	w.opened = time.Now()
+	if w.size > 0 {
+		w.opened = w.started(info)
+	}
+	return nil
+}
+
+// started works out when an existing log file was begun, so a restart
+// doesn't reset the age clock: the timestamp on its first line, otherwise
+// the newest backup's suffix, which is when the file was last rotated. The
+// modification time is a last resort.
+func (w *rotatingWriter) started(info os.FileInfo) time.Time {
+	if f, err := os.Open(w.path); err == nil {
+		defer f.Close()
+		first := make([]byte, len("2006/01/02 15:04:05"))
+		if _, err := io.ReadFull(f, first); err == nil {
+			if t, err := time.ParseInLocation("2006/01/02 15:04:05", string(first), time.Local); err == nil {
+				return t
+			}
+		}
+	}
+	if backups := w.backups(); len(backups) > 0 {
+		suffix := strings.TrimPrefix(backups[0], w.path+".")
+		if t, err := time.ParseInLocation("20060102-150405", suffix, time.Local); err == nil {
+			return t
+		}
+	}
+	return info.ModTime()
+}
+
+func (w *rotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	tooBig := w.maxSize > 0 && w.size+int64(len(p)) > w.maxSize && w.size > 0
+	tooOld := w.maxFileAge > 0 && time.Since(w.opened) > w.maxFileAge && w.size > 0
+	if tooBig || tooOld {
+		if err := w.rotate(); err != nil {
+			// keep writing to the current file rather than losing logs
+			os.Stderr.WriteString("log rotation failed: " + err.Error() + "\n")
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+func (w *rotatingWriter) rotate() error {
+	w.file.Close()
+	backup := w.path + "." + time.Now().Format("20060102-150405")
+	if err := os.Rename(w.path, backup); err != nil {
+		if openErr := w.open(); openErr != nil {
+			return openErr
+		}
+		return err
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+	w.prune()
+	return nil
+}
+
+// backups lists the rotated files, newest first; the timestamp suffix sorts
+// chronologically
+func (w *rotatingWriter) backups() []string {
+	matches, err := filepath.Glob(w.path + ".*")
+	if err != nil {
+		return nil
+	}
+	var backups []string
+	for _, m := range matches {
+		if len(strings.TrimPrefix(m, w.path+".")) == len("20060102-150405") {
+			backups = append(backups, m)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+// prune removes rotated files beyond maxBackups or older than maxAge
+func (w *rotatingWriter) prune() {
+	for i, b := range w.backups() {
+		info, err := os.Stat(b)
+		if err != nil {
+			continue
+		}
+		if (w.maxBackups > 0 && i >= w.maxBackups) || (w.maxAge > 0 && time.Since(info.ModTime()) > w.maxAge) {
+			os.Remove(b)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// metoffice.go
+package main
+
+import (
+	"html"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const metOfficeURL = "https://www.metoffice.gov.uk/weather/specialist-forecasts/space-weather"
+
+var (
+	htmlSkipPattern = regexp.MustCompile(`(?is)<(script|style|noscript)[^>]*>.*?</(script|style|noscript)>`)
+	htmlTagPattern  = regexp.MustCompile(`<[^>]*>`)
+	sentencePattern = regexp.MustCompile(`[.!?]\s+`)
+	clausePattern   = regexp.MustCompile(`[,;]\s+|\s+(?:but|while|whereas|although)\s+`)
+	negationPattern = regexp.MustCompile(`(?i)\b(?:no|not|unlikely|none|nor)\b`)
+	likelyPattern   = regexp.MustCompile(`(?i)\b(?:likely|expected)\b`)
+)
+
+// processMetOffice checks the Met Office Space Weather Operations Centre
+// (MOSWOC) forecast and alerts for G/S/R 3 or above
+func processMetOffice(store StateStore) (Severity, error) {
+	lines, err := fetchMetOffice()
+	if err != nil {
+		log.Println("Error fetching Met Office space weather:", err)
+		return SeverityOK, err
+	}
+	notes := evaluateMetOffice(lines, time.Now().UTC())
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// fetchMetOffice returns the non-empty text lines of the MOSWOC page, or of
+// a plain-text feed if metoffice_url points at one
+func fetchMetOffice() ([]string, error) {
+	req, err := http.NewRequest("GET", config.MetOfficeURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if config.MetOfficeAPIKey != "" {
+		req.Header.Set("apikey", config.MetOfficeAPIKey)
+	}
+	var lines []string
+	err = fetchRequest("metoffice", req, func(body io.Reader) error {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return err
+		}
+		lines = textLines(string(data))
+		return nil
+	})
+	return lines, err
+}
+
+// textLines strips markup from a page and splits it into trimmed lines
+func textLines(page string) []string {
+	page = htmlSkipPattern.ReplaceAllString(page, "")
+	page = htmlTagPattern.ReplaceAllString(page, "\n")
+	page = html.UnescapeString(page)
+	var lines []string
+	for _, line := range strings.Split(page, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// evaluateMetOffice alerts on forecast and alert clauses that say a G, S or
+// R level of 3 or more is likely or expected; MOSWOC uses the NOAA
+// scales. Short lines are headings and scale legends, not forecasts, and
+// the analysis of the past 24 hours is skipped.
+func evaluateMetOffice(lines []string, now time.Time) []Notification {
+	var notes []Notification
+	sectioned := false
+	for _, line := range lines {
+		if _, ok := metOfficeSection(line); ok {
+			sectioned = true
+			break
+		}
+	}
+	inForecast := !sectioned
+	for _, line := range lines {
+		if forecast, ok := metOfficeSection(line); ok {
+			inForecast = forecast
+			continue
+		}
+		if !inForecast || len(line) < 40 {
+			continue
+		}
+		level := 0
+		for _, sentence := range forecastSentences(line) {
+			for _, clause := range sentence {
+				if negated(clause) || !likelyPattern.MatchString(clause) {
+					continue
+				}
+				if l := noaaScaleLevel(clause); l > level {
+					level = l
+				}
+			}
+		}
+		if level < 3 {
+			continue
+		}
+		severity := SeverityWarning
+		if level >= 4 {
+			severity = SeverityCritical
+		}
+		msg := renderMessage("metoffice", map[string]interface{}{"Message": line})
+		notes = append(notes, Notification{Key: hashAlert(msg), Rule: "metoffice", Text: msg, Severity: severity, Time: now})
+	}
+	return notes
+}
+
+// metOfficeSection reports whether a line is a section heading, and if so
+// whether the section is a forecast or alert rather than an analysis
+func metOfficeSection(line string) (bool, bool) {
+	if len(line) >= 40 {
+		return false, false
+	}
+	lower := strings.ToLower(line)
+	switch {
+	case strings.Contains(lower, "analysis"), strings.Contains(lower, "past 24 hours"), strings.Contains(lower, "observed"):
+		return false, true
+	case strings.Contains(lower, "forecast"), strings.Contains(lower, "alert"), strings.Contains(lower, "warning"):
+		return true, true
+	}
+	return false, false
+}
+
+// forecastSentences splits forecast text into sentences of clauses, so
+// wording like "minor storms likely, strong storms unlikely" can be judged
+// a clause at a time
+func forecastSentences(text string) [][]string {
+	var sentences [][]string
+	for _, sentence := range sentencePattern.Split(text, -1) {
+		sentences = append(sentences, clausePattern.Split(sentence, -1))
+	}
+	return sentences
+}
+
+// negated reports whether a forecast clause rules its event out
+func negated(clause string) bool {
+	return negationPattern.MatchString(clause)
+}
@@ -0,0 +1,180 @@
+// mqtt.go
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"time"
+)
+
+// mqttConn is a minimal MQTT 3.1.1 client: connect, subscribe and receive
+// messages, and keepalive pings
+type mqttConn struct {
+	conn      net.Conn
+	rd        *bufio.Reader
+	keepalive time.Duration
+	packetID  uint16
+}
+
+// mqttMessage is an application message received on a subscription
+type mqttMessage struct {
+	Topic   string
+	Payload []byte
+}
+
+// dialMQTT connects to a broker URL such as tcp://host:1883 or ssl://host:8883
+func dialMQTT(broker, clientID, username, password string) (*mqttConn, error) {
+	u, err := url.Parse(broker)
+	if err != nil {
+		return nil, err
+	}
+	var conn net.Conn
+	switch u.Scheme {
+	case "tcp", "mqtt":
+		conn, err = net.DialTimeout("tcp", u.Host, 10*time.Second)
+	case "ssl", "tls", "mqtts":
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", u.Host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("unsupported MQTT scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &mqttConn{conn: conn, rd: bufio.NewReader(conn), keepalive: 60 * time.Second}
+
+	flags := byte(0x02) // clean session
+	payload := mqttString(clientID)
+	if username != "" {
+		flags |= 0x80
+		payload = append(payload, mqttString(username)...)
+	}
+	if password != "" {
+		flags |= 0x40
+		payload = append(payload, mqttString(password)...)
+	}
+	variable := append(mqttString("MQTT"), 4, flags, byte(c.keepalive/time.Second>>8), byte(c.keepalive/time.Second))
+	if err := c.write(0x10, append(variable, payload...)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	kind, body, err := c.readPacket()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if kind>>4 != 2 || len(body) < 2 {
+		conn.Close()
+		return nil, fmt.Errorf("expected CONNACK, got packet type %d", kind>>4)
+	}
+	if body[1] != 0 {
+		conn.Close()
+		return nil, fmt.Errorf("broker refused connection (code %d)", body[1])
+	}
+	return c, nil
+}
+
+func (c *mqttConn) Close() error {
+	c.write(0xe0, nil) // DISCONNECT
+	return c.conn.Close()
+}
+
+func (c *mqttConn) Subscribe(topic string) error {
+	c.packetID++
+	body := []byte{byte(c.packetID >> 8), byte(c.packetID)}
+	body = append(body, mqttString(topic)...)
+	body = append(body, 0) // QoS 0
+	return c.write(0x82, body)
+}
+
+// ReadMessage blocks until a PUBLISH arrives, sending pings while idle
+func (c *mqttConn) ReadMessage() (mqttMessage, error) {
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(c.keepalive / 2))
+		kind, body, err := c.readPacket()
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() {
+			if err := c.write(0xc0, nil); err != nil { // PINGREQ
+				return mqttMessage{}, err
+			}
+			continue
+		}
+		if err != nil {
+			return mqttMessage{}, err
+		}
+		if kind>>4 != 3 {
+			continue // SUBACK, PINGRESP, ...
+		}
+		if len(body) < 2 {
+			return mqttMessage{}, fmt.Errorf("short PUBLISH packet")
+		}
+		n := int(body[0])<<8 | int(body[1])
+		if len(body) < 2+n {
+			return mqttMessage{}, fmt.Errorf("short PUBLISH packet")
+		}
+		msg := mqttMessage{Topic: string(body[2 : 2+n])}
+		rest := body[2+n:]
+		if qos := (kind >> 1) & 0x03; qos > 0 && len(rest) >= 2 {
+			if qos == 1 {
+				c.write(0x40, rest[:2]) // PUBACK
+			}
+			rest = rest[2:]
+		}
+		msg.Payload = rest
+		return msg, nil
+	}
+}
+
+func (c *mqttConn) write(header byte, body []byte) error {
+	packet := []byte{header}
+	n := len(body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		packet = append(packet, b)
+		if n == 0 {
+			break
+		}
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	_, err := c.conn.Write(append(packet, body...))
+	return err
+}
+
+func (c *mqttConn) readPacket() (byte, []byte, error) {
+	kind, err := c.rd.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		b, err := c.rd.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+		if i == 3 {
+			return 0, nil, fmt.Errorf("malformed MQTT remaining length")
+		}
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(c.rd, body)
+	return kind, body, err
+}
+
+func mqttString(s string) []byte {
+	return append([]byte{byte(len(s) >> 8), byte(len(s))}, s...)
+}
@@ -0,0 +1,76 @@
+// nict.go
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+)
+
+const nictURL = "https://swc.nict.go.jp/en/"
+
+// nictTerms maps the NICT Space Weather Forecast Center's warning wording to
+// a severity; the forecasts use categories rather than the NOAA scales
+var nictTerms = []struct {
+	phrase   string
+	severity Severity
+}{
+	{"major storm", SeverityCritical},
+	{"severe storm", SeverityCritical},
+	{"minor storm", SeverityWarning},
+	{"major flare", SeverityWarning},
+	{"x-class flare", SeverityWarning},
+	{"proton event", SeverityWarning},
+}
+
+// processNICT checks the NICT forecast and warnings for storm, flare and
+// proton wording
+func processNICT(store StateStore) (Severity, error) {
+	var lines []string
+	err := fetchAndDecode("nict", config.NICTURL, func(body io.Reader) error {
+		data, err := ioutil.ReadAll(body)
+		lines = textLines(string(data))
+		return err
+	})
+	if err != nil {
+		log.Println("Error fetching NICT forecast:", err)
+		return SeverityOK, err
+	}
+	notes := evaluateNICT(lines, time.Now().UTC())
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// evaluateNICT alerts on each forecast sentence using NICT's warning terms,
+// at the highest severity it mentions outside a negated clause, so "no major
+// storm is expected" doesn't count
+func evaluateNICT(lines []string, now time.Time) []Notification {
+	var notes []Notification
+	for _, line := range lines {
+		if len(line) < 40 {
+			continue
+		}
+		severity := SeverityOK
+		for _, sentence := range forecastSentences(line) {
+			for _, clause := range sentence {
+				if negated(clause) {
+					continue
+				}
+				lower := strings.ToLower(clause)
+				for _, t := range nictTerms {
+					if strings.Contains(lower, t.phrase) && t.severity > severity {
+						severity = t.severity
+					}
+				}
+			}
+		}
+		if severity == SeverityOK {
+			continue
+		}
+		msg := renderMessage("nict", map[string]interface{}{"Message": line})
+		notes = append(notes, Notification{Key: hashAlert(msg), Rule: "nict", Text: msg, Severity: severity, Time: now})
+	}
+	return notes
+}
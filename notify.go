@@ -0,0 +1,180 @@
+// notify.go
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// Notification is an alert produced by a rule, ready for delivery
+type Notification struct {
+	Key      string // dedup key claimed in the state store
+	OldKey   string // key an earlier version claimed for the same alert, if any
+	Rule     string
+	Category string // see alertCategory; set by notify when empty
+	Text     string
+	Summary  string // short form for SMS; Text is sent when empty
+	Severity Severity
+	Time     time.Time // when the triggering data was observed or issued
+}
+
+// Notifier delivers notifications over one channel
+type Notifier interface {
+	Name() string
+	// Render returns the text exactly as the channel would send it
+	Render(n Notification) string
+	Send(n Notification) error
+}
+
+// smsNotifier texts one number; the twilio_to number is the "sms" channel
+// and each of sms_recipients is "sms:<number>"
+type smsNotifier struct {
+	to         string
+	categories []string
+}
+
+func (s smsNotifier) Name() string {
+	if s.to == config.TwilioTo {
+		return "sms"
+	}
+	return "sms:" + s.to
+}
+
+func (smsNotifier) Render(n Notification) string {
+	if n.Summary != "" {
+		return n.Summary
+	}
+	return n.Text
+}
+
+func (s smsNotifier) Send(n Notification) error { return sendSMSTo(s.to, s.Render(n)) }
+
+// SMSRecipient is an extra number to text, optionally limited to some
+// alert categories
+type SMSRecipient struct {
+	To         string   `json:"to" desc:"Number in E.164 format"`
+	Categories []string `json:"categories" desc:"Categories to send (geomagnetic, radiation, radio, aurora, system-health); all when empty"`
+}
+
+func configuredNotifiers() []Notifier {
+	notifiers := []Notifier{smsNotifier{to: config.TwilioTo}}
+	for _, r := range mergedRecipients() {
+		notifiers = append(notifiers, smsNotifier{to: r.To, categories: r.Categories})
+	}
+	if config.SNMPTrapTarget != "" {
+		notifiers = append(notifiers, snmpNotifier{})
+	}
+	if eventLogAvailable() {
+		notifiers = append(notifiers, eventLogNotifier{})
+	}
+	return notifiers
+}
+
+// mergedRecipients folds sms_recipients that repeat a number into one,
+// since each number is one channel with one queue. A repeat of twilio_to is
+// left out: that number already gets every category.
+func mergedRecipients() []SMSRecipient {
+	var merged []SMSRecipient
+	index := make(map[string]int)
+	for _, r := range config.SMSRecipients {
+		if r.To == config.TwilioTo {
+			continue
+		}
+		i, seen := index[r.To]
+		if !seen {
+			index[r.To] = len(merged)
+			merged = append(merged, SMSRecipient{To: r.To, Categories: append([]string(nil), r.Categories...)})
+			continue
+		}
+		// no categories means all of them
+		if len(merged[i].Categories) == 0 || len(r.Categories) == 0 {
+			merged[i].Categories = nil
+			continue
+		}
+		for _, c := range r.Categories {
+			if !subscribed(merged[i].Categories, c) {
+				merged[i].Categories = append(merged[i].Categories, c)
+			}
+		}
+	}
+	return merged
+}
+
+// routeNotification returns the channels subscribed to the notification's
+// category, per recipient and per channel
+func routeNotification(n Notification) []Notifier {
+	category := n.Category
+	if category == "" {
+		category = alertCategory(n)
+	}
+	var channels []Notifier
+	for _, ch := range configuredNotifiers() {
+		if sms, ok := ch.(smsNotifier); ok && !subscribed(sms.categories, category) {
+			continue
+		}
+		if cats, ok := config.Subscriptions[ch.Name()]; ok && !subscribed(cats, category) {
+			continue
+		}
+		channels = append(channels, ch)
+	}
+	return channels
+}
+
+// notify queues each notification not already claimed in the store and
+// reports the keys of any dropped because every queue was full
+func notify(store StateStore, notes []Notification) map[string]bool {
+	dropped := make(map[string]bool)
+	for _, n := range notes {
+		if n.Category == "" {
+			n.Category = alertCategory(n)
+		}
+		fresh := store.Claim(n.Key)
+		// claim the old key too so caches written before the key changed
+		// still suppress the alert
+		if n.OldKey != "" && !store.Claim(n.OldKey) {
+			fresh = false
+		}
+		if !fresh {
+			emitAlertDecision(n, "duplicate", nil)
+			continue
+		}
+		if currentControl().muted(time.Now()) {
+			log.Printf("Muted: not sending %s alert", n.Rule)
+			emitAlertDecision(n, "muted", nil)
+			recordHistory(store, n, nil)
+			continue
+		}
+		channels := routeNotification(n)
+		if len(channels) == 0 {
+			emitAlertDecision(n, "unsubscribed", nil)
+			recordHistory(store, n, nil)
+			continue
+		}
+		queued := notifications.enqueue(n, channels)
+		if len(queued) == 0 {
+			// every queue was full: give the claim back so the next poll
+			// tries again instead of losing the alert
+			store.Release(n.Key)
+			if n.OldKey != "" {
+				store.Release(n.OldKey)
+			}
+			emitAlertDecision(n, "dropped", nil)
+			dropped[n.Key] = true
+			continue
+		}
+		emitAlertDecision(n, "sent", queued)
+		recordHistory(store, n, queued)
+	}
+	return dropped
+}
+
+func emitAlertDecision(n Notification, decision string, channels []Notifier) {
+	names := []string{}
+	for _, ch := range channels {
+		names = append(names, ch.Name())
+	}
+	emitJSONL("alert", map[string]interface{}{
+		"rule": n.Rule, "category": n.Category, "severity": n.Severity.String(), "key": n.Key,
+		"decision": decision, "channels": names, "text": n.Text, "time": n.Time,
+	})
+}
@@ -0,0 +1,109 @@
+// reading_history.go
+package main
+
+import (
+	"log"
+	"math"
+	"sync"
+	"time"
+)
+
+// readingHistoryDays is how much reading history is kept: one solar rotation
+// plus a day
+const readingHistoryDays = 28
+
+// HistorySample is one stored reading; keys are short since a month of
+// polls adds up
+type HistorySample struct {
+	Time  time.Time `json:"t"`
+	Value float64   `json:"v"`
+}
+
+// ReadingHistory holds the recent samples of each metric, oldest first
+type ReadingHistory map[string][]HistorySample
+
+var (
+	historyMu     sync.Mutex
+	recentHistory = make(ReadingHistory)
+)
+
+// loadReadingHistory reads the stored history for this poll's alerts
+func loadReadingHistory(store StateStore) {
+	hist := make(ReadingHistory)
+	if err := store.Get("reading_history", &hist); err != nil {
+		log.Println("Error reading reading history:", err)
+		return
+	}
+	historyMu.Lock()
+	recentHistory = hist
+	historyMu.Unlock()
+}
+
+// updateReadingHistory appends the readings from this poll to the history,
+// drops samples older than readingHistoryDays, saves it and returns a copy
+func updateReadingHistory(store StateStore, readings map[string]Reading, now time.Time) ReadingHistory {
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	cutoff := now.Add(-readingHistoryDays * 24 * time.Hour)
+	for metric, r := range readings {
+		samples := recentHistory[metric]
+		if n := len(samples); n == 0 || r.Time.After(samples[n-1].Time) {
+			samples = append(samples, HistorySample{Time: r.Time, Value: r.Value})
+		}
+		recentHistory[metric] = samples
+	}
+	hist := make(ReadingHistory, len(recentHistory))
+	for metric, samples := range recentHistory {
+		i := 0
+		for i < len(samples) && samples[i].Time.Before(cutoff) {
+			i++
+		}
+		recentHistory[metric] = samples[i:]
+		hist[metric] = append([]HistorySample(nil), samples[i:]...)
+	}
+	if err := store.Put("reading_history", hist); err != nil {
+		log.Println("Error saving reading history:", err)
+	}
+	return hist
+}
+
+// range24h is the lowest and highest value of metric in the 24 hours up to
+// the newest sample of series, from stored history and series itself
+func range24h(metric string, series []Sample) (lo, hi float64) {
+	latest := series[len(series)-1]
+	lo, hi = latest.Value, latest.Value
+	end, err := parseTimeTag(latest.TimeTag)
+	if err != nil {
+		return lo, hi
+	}
+	start := end.Add(-24 * time.Hour)
+	include := func(t time.Time, v float64) {
+		if t.Before(start) || t.After(end) {
+			return
+		}
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	for _, s := range series {
+		if t, err := parseTimeTag(s.TimeTag); err == nil {
+			include(t, s.Value)
+		}
+	}
+	historyMu.Lock()
+	defer historyMu.Unlock()
+	for _, s := range recentHistory[metric] {
+		include(s.Time, s.Value)
+	}
+	return lo, hi
+}
+
+// dailyMax returns the largest value per UTC day, keyed by date
+func dailyMax(samples []HistorySample) map[string]float64 {
+	days := make(map[string]float64)
+	for _, s := range samples {
+		day := s.Time.UTC().Format("2006-01-02")
+		if v, ok := days[day]; !ok || s.Value > v {
+			days[day] = s.Value
+		}
+	}
+	return days
+}
@@ -0,0 +1,42 @@
+// readings.go
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// Reading is the latest observed value of a metric
+type Reading struct {
+	Metric string    `json:"metric"`
+	Value  float64   `json:"value"`
+	Time   time.Time `json:"time"`
+	Source string    `json:"source"`
+}
+
+var (
+	readingsMu     sync.Mutex
+	latestReadings = make(map[string]Reading)
+)
+
+// recordReading stores the newest value of metric as reported by source
+func recordReading(metric, source string, value float64, timeTag string) {
+	t, err := parseTimeTag(timeTag)
+	if err != nil {
+		t = time.Now().UTC()
+	}
+	readingsMu.Lock()
+	latestReadings[metric] = Reading{Metric: metric, Value: value, Time: t, Source: source}
+	readingsMu.Unlock()
+	emitJSONL("reading", map[string]interface{}{"metric": metric, "value": value, "source": source, "time": t})
+}
+
+func currentReadings() map[string]Reading {
+	readingsMu.Lock()
+	defer readingsMu.Unlock()
+	out := make(map[string]Reading, len(latestReadings))
+	for k, v := range latestReadings {
+		out[k] = v
+	}
+	return out
+}
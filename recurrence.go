@@ -0,0 +1,42 @@
+// recurrence.go
+package main
+
+import "time"
+
+// solarRotation is the synodic rotation period as seen from Earth; coronal
+// holes that drove a storm often drive another one rotation later
+const solarRotation = 27 * 24 * time.Hour
+
+// evaluateRecurrence gives a heads-up when a day one rotation ago reached
+// recurrence_kp and its repeat falls within the next recurrence_lead_days
+func evaluateRecurrence(hist ReadingHistory, now time.Time) []Notification {
+	if config.RecurrenceKp <= 0 {
+		return nil
+	}
+	var notes []Notification
+	today := now.UTC().Truncate(24 * time.Hour)
+	for day, kp := range dailyMax(hist["kp"]) {
+		if kp < config.RecurrenceKp {
+			continue
+		}
+		storm, err := time.Parse("2006-01-02", day)
+		if err != nil {
+			continue
+		}
+		repeat := storm.Add(solarRotation)
+		if repeat.Before(today) || repeat.After(today.AddDate(0, 0, config.RecurrenceLeadDays)) {
+			continue
+		}
+		msg := renderMessage("recurrence", map[string]interface{}{
+			"Kp": kp, "Day": day, "Date": repeat.Format("Mon 2 Jan"),
+		})
+		notes = append(notes, Notification{
+			Key:      hashAlert("recurrence " + day),
+			Rule:     "recurrence",
+			Text:     msg,
+			Severity: SeverityOK,
+			Time:     now,
+		})
+	}
+	return notes
+}
@@ -0,0 +1,88 @@
+// render.go
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// runRender renders the template for a rule with sample values, overridden
+// by Key=value arguments, and prints it as each channel would send it
+func runRender(args []string) {
+	if len(args) == 0 || defaultTemplates[args[0]] == "" {
+		rules := make([]string, 0, len(defaultTemplates))
+		for rule := range defaultTemplates {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+		fmt.Fprintf(os.Stderr, "usage: space_alerts render <%s> [Key=value ...]\n", strings.Join(rules, "|"))
+		os.Exit(2)
+	}
+	rule := args[0]
+
+	data := make(map[string]interface{})
+	for k, v := range templateSamples[rule] {
+		data[k] = v
+	}
+	for _, arg := range args[1:] {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			fmt.Fprintf(os.Stderr, "expected Key=value, got %q\n", arg)
+			os.Exit(2)
+		}
+		if f, err := strconv.ParseFloat(kv[1], 64); err == nil {
+			data[kv[0]] = f
+		} else {
+			data[kv[0]] = strings.ReplaceAll(kv[1], `\n`, "\n")
+		}
+	}
+
+	text, err := executeTemplate(messageTemplate(rule), data)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "template for %s is invalid: %v\n", rule, err)
+		os.Exit(1)
+	}
+	n := Notification{Key: hashAlert(text), Rule: rule, Text: text}
+	if rule == "swpc_alert" {
+		n.Summary = swpcSummary(fmt.Sprint(data["Message"]))
+	}
+	for _, ch := range configuredNotifiers() {
+		out := ch.Render(n)
+		fmt.Printf("--- %s", ch.Name())
+		if ch.Name() == "sms" {
+			segments, encoding := smsSegments(out)
+			fmt.Printf(" (%d segment(s), %s)", segments, encoding)
+		}
+		fmt.Printf(" ---\n%s\n\n", out)
+	}
+}
+
+const gsm7Chars = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?" +
+	"¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+
+// smsSegments estimates how many SMS parts a message needs
+func smsSegments(text string) (int, string) {
+	gsm := true
+	for _, r := range text {
+		if !strings.ContainsRune(gsm7Chars, r) {
+			gsm = false
+			break
+		}
+	}
+	if gsm {
+		n := len([]rune(text))
+		if n <= 160 {
+			return 1, "GSM-7"
+		}
+		return (n + 152) / 153, "GSM-7"
+	}
+	n := len(utf16.Encode([]rune(text)))
+	if n <= 70 {
+		return 1, "UCS-2"
+	}
+	return (n + 66) / 67, "UCS-2"
+}
@@ -0,0 +1,135 @@
+// sep_hardness.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// ProtonSpectrum is the integral proton flux in the GOES channels that
+// matter for spectral hardness at one time tag, in pfu
+type ProtonSpectrum struct {
+	TimeTag                 string
+	Flux10, Flux50, Flux100 float64
+	has10, has50, has100    bool
+}
+
+// SEPEvent is the hard-spectrum event in progress, kept in the sep_hardness
+// state document so its start survives the 1-day window moving past it
+type SEPEvent struct {
+	Began string `json:"began,omitempty"`
+}
+
+// processSEPHardness compares the proton channels to catch hard-spectrum
+// events, which carry the avionics, EVA and GLE risk ordinary S1 events
+// don't
+func processSEPHardness(store StateStore) (Severity, error) {
+	var readings []FluxReading
+	err := fetchJSON("goes_protons", protonFluxURL, &readings)
+	spectra := protonSpectra(readings)
+	if err == nil && len(spectra) == 0 {
+		err = fmt.Errorf("no proton readings with >=10, >=50 and >=100 MeV channels")
+	}
+	if err != nil {
+		log.Println("Error fetching proton flux:", err)
+		return SeverityOK, err
+	}
+	latest := spectra[len(spectra)-1]
+	recordTimeTag("goes_protons", latest.TimeTag)
+	recordReading("proton_flux", "goes_protons", latest.Flux10, latest.TimeTag)
+	recordReading("proton_flux_100", "goes_protons", latest.Flux100, latest.TimeTag)
+
+	var event SEPEvent
+	if err := store.Get("sep_hardness", &event); err != nil {
+		log.Println("Error reading SEP event:", err)
+		return SeverityOK, err
+	}
+	event = trackSEPEvent(event, spectra)
+	if err := store.Put("sep_hardness", event); err != nil {
+		log.Println("Error saving SEP event:", err)
+	}
+	notes := evaluateSEPHardness(spectra, event)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// protonSpectra groups a GOES integral proton product by time tag, oldest
+// first, keeping only times with all three channels
+func protonSpectra(readings []FluxReading) []ProtonSpectrum {
+	byTime := make(map[string]*ProtonSpectrum)
+	for _, r := range readings {
+		s, ok := byTime[r.TimeTag]
+		if !ok {
+			s = &ProtonSpectrum{TimeTag: r.TimeTag}
+			byTime[r.TimeTag] = s
+		}
+		switch r.Energy {
+		case ">=10 MeV":
+			s.Flux10, s.has10 = r.Flux, true
+		case ">=50 MeV":
+			s.Flux50, s.has50 = r.Flux, true
+		case ">=100 MeV":
+			s.Flux100, s.has100 = r.Flux, true
+		}
+	}
+	var spectra []ProtonSpectrum
+	for _, s := range byTime {
+		if s.has10 && s.has50 && s.has100 && s.Flux10 > 0 {
+			spectra = append(spectra, *s)
+		}
+	}
+	sort.Slice(spectra, func(i, j int) bool { return spectra[i].TimeTag < spectra[j].TimeTag })
+	return spectra
+}
+
+// hard reports whether the spectrum is a hard-spectrum event: ≥100 MeV
+// flux at sep_hard_flux or above and at least sep_hard_ratio of ≥10 MeV
+func (s ProtonSpectrum) hard() bool {
+	return s.Flux100 >= config.SEPHardFlux && s.Flux100/s.Flux10 >= config.SEPHardRatio
+}
+
+// trackSEPEvent records when the current hard interval began, walking back
+// through the window the first time it is seen and keeping that start for as
+// long as the newest spectrum stays hard
+func trackSEPEvent(event SEPEvent, spectra []ProtonSpectrum) SEPEvent {
+	if config.SEPHardFlux <= 0 || len(spectra) == 0 || !spectra[len(spectra)-1].hard() {
+		return SEPEvent{}
+	}
+	if event.Began != "" {
+		return event
+	}
+	event.Began = spectra[len(spectra)-1].TimeTag
+	for i := len(spectra) - 2; i >= 0 && spectra[i].hard(); i-- {
+		event.Began = spectra[i].TimeTag
+	}
+	return event
+}
+
+// evaluateSEPHardness alerts while the newest spectrum is hard, keyed on when
+// the event began so one event alerts once per level. Ten times
+// sep_hard_flux at ≥100 MeV is critical.
+func evaluateSEPHardness(spectra []ProtonSpectrum, event SEPEvent) []Notification {
+	if event.Began == "" || len(spectra) == 0 {
+		return nil
+	}
+	latest := spectra[len(spectra)-1]
+	began := event.Began
+
+	severity := SeverityWarning
+	if latest.Flux100 >= 10*config.SEPHardFlux {
+		severity = SeverityCritical
+	}
+	observed, _ := parseTimeTag(latest.TimeTag)
+	msg := renderMessage("sep_hardness", map[string]interface{}{
+		"Flux10": latest.Flux10, "Flux50": latest.Flux50, "Flux100": latest.Flux100,
+		"Percent": 100 * latest.Flux100 / latest.Flux10, "TimeTag": latest.TimeTag,
+	})
+	return []Notification{{
+		Key:      hashAlert("sep_hardness " + began + " " + severity.String()),
+		Rule:     "sep_hardness",
+		Text:     msg,
+		Severity: severity,
+		Time:     observed,
+	}}
+}
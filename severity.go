@@ -0,0 +1,81 @@
+// severity.go
+package main
+
+import (
+	"regexp"
+	"time"
+)
+
+// Severity ranks how serious a condition is
+type Severity int
+
+const (
+	SeverityOK Severity = iota
+	SeverityWarning
+	SeverityCritical
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityCritical:
+		return "critical"
+	}
+	return "ok"
+}
+
+var noaaScalePattern = regexp.MustCompile(`\b[GSR]([1-5])\b`)
+
+// noaaScaleLevel returns the highest G/S/R scale number mentioned in msg
+func noaaScaleLevel(msg string) int {
+	level := 0
+	for _, m := range noaaScalePattern.FindAllStringSubmatch(msg, -1) {
+		if l := int(m[1][0] - '0'); l > level {
+			level = l
+		}
+	}
+	return level
+}
+
+// activeSeverity is the highest severity among notifications observed in the
+// last 24 hours; older SWPC products stay in the feed but are no longer active
+func activeSeverity(notes []Notification) Severity {
+	highest := SeverityOK
+	for _, n := range notes {
+		if !n.Time.IsZero() && time.Since(n.Time) > 24*time.Hour {
+			continue
+		}
+		if n.Severity > highest {
+			highest = n.Severity
+		}
+	}
+	return highest
+}
+
+// pollResult summarizes one pass over all sources
+type pollResult struct {
+	Severity    Severity
+	FetchFailed bool
+}
+
+// Exit codes for --once: critical outranks a fetch failure, which outranks
+// a warning, since a partial fetch can't prove things are only a warning
+const (
+	exitOK          = 0
+	exitWarning     = 1
+	exitCritical    = 2
+	exitFetchFailed = 3
+)
+
+func (r pollResult) exitCode() int {
+	switch {
+	case r.Severity == SeverityCritical:
+		return exitCritical
+	case r.FetchFailed:
+		return exitFetchFailed
+	case r.Severity == SeverityWarning:
+		return exitWarning
+	}
+	return exitOK
+}
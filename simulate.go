@@ -0,0 +1,146 @@
+// simulate.go
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"strings"
+	"time"
+)
+
+// runSimulate runs a saved product snapshot through the alert rules and
+// prints what would be sent, without touching dedup state or any channel
+func runSimulate(args []string) {
+	fs := flag.NewFlagSet("simulate", flag.ExitOnError)
+	file := fs.String("file", "", "SWPC product JSON file to evaluate")
+	product := fs.String("product", "", "product type: alerts, kp, bz, xrays, electrons, protons, cactus, metoffice or nict (detected when empty)")
+	fs.Parse(args)
+	if *file == "" {
+		log.Fatal("simulate requires --file")
+	}
+
+	data, err := ioutil.ReadFile(*file)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", *file, err)
+	}
+	if *product == "" {
+		*product = detectProduct(data)
+	}
+
+	notes, records, err := evaluateProduct(*product, data)
+	if err != nil {
+		log.Fatalf("Failed to evaluate %s: %v", *file, err)
+	}
+	fmt.Printf("Product: %s (%d records)\n", *product, records)
+	if len(notes) == 0 {
+		fmt.Println("No alerts would fire.")
+		return
+	}
+	fmt.Printf("%d alert(s) would fire (dedup state ignored):\n", len(notes))
+	for i, n := range notes {
+		fmt.Printf("\n[%d] rule=%s category=%s severity=%s\n", i+1, n.Rule, alertCategory(n), n.Severity)
+		for _, ch := range routeNotification(n) {
+			fmt.Printf("  -> %s\n", ch.Name())
+			fmt.Println(indent(ch.Render(n), "    "))
+		}
+	}
+}
+
+func evaluateProduct(product string, data []byte) ([]Notification, int, error) {
+	switch product {
+	case "alerts":
+		var alerts []Alert
+		if err := json.Unmarshal(data, &alerts); err != nil {
+			return nil, 0, err
+		}
+		return evaluateSWPCAlerts(alerts), len(alerts), nil
+	case "kp":
+		kpList, err := decodeSeries(data, "time_tag", "kp_index")
+		if err != nil || len(kpList) == 0 {
+			return nil, 0, fmt.Errorf("no Kp readings: %v", err)
+		}
+		return evaluateKpIndex(kpList), len(kpList), nil
+	case "bz":
+		bzList, err := decodeSeries(data, "time_tag", "bz_gsm")
+		if err != nil || len(bzList) == 0 {
+			return nil, 0, fmt.Errorf("no Bz readings: %v", err)
+		}
+		return append(evaluateBzField(bzList), evaluateBzFlip(bzList)...), len(bzList), nil
+	case "metoffice":
+		lines := textLines(string(data))
+		return evaluateMetOffice(lines, time.Now().UTC()), len(lines), nil
+	case "xrays":
+		var readings []FluxReading
+		if err := json.Unmarshal(data, &readings); err != nil {
+			return nil, 0, err
+		}
+		series := fluxSeries(readings, "0.1-0.8nm")
+		if len(series) == 0 {
+			return nil, 0, fmt.Errorf("no 0.1-0.8nm X-ray readings")
+		}
+		return evaluateFlareOnset(series), len(series), nil
+	case "electrons":
+		var readings []FluxReading
+		if err := json.Unmarshal(data, &readings); err != nil {
+			return nil, 0, err
+		}
+		series := fluxSeries(readings, ">=2 MeV")
+		if len(series) == 0 {
+			return nil, 0, fmt.Errorf("no >=2 MeV electron readings")
+		}
+		fluence := accumulateFluence(ElectronFluence{}, series)
+		return evaluateElectronFluence(fluence, series[len(series)-1].Value), len(series), nil
+	case "protons":
+		var readings []FluxReading
+		if err := json.Unmarshal(data, &readings); err != nil {
+			return nil, 0, err
+		}
+		spectra := protonSpectra(readings)
+		if len(spectra) == 0 {
+			return nil, 0, fmt.Errorf("no proton readings with >=10, >=50 and >=100 MeV channels")
+		}
+		event := trackSEPEvent(SEPEvent{}, spectra)
+		return evaluateSEPHardness(spectra, event), len(spectra), nil
+	case "cactus":
+		cmes, err := parseCACTus(bytes.NewReader(data))
+		if err != nil {
+			return nil, 0, err
+		}
+		return evaluateCACTus(cmes, time.Now().UTC()), len(cmes), nil
+	case "nict":
+		lines := textLines(string(data))
+		return evaluateNICT(lines, time.Now().UTC()), len(lines), nil
+	}
+	return nil, 0, fmt.Errorf("unknown product %q", product)
+}
+
+// detectProduct guesses the product type from the fields of the first record
+func detectProduct(data []byte) string {
+	var records []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &records); err != nil || len(records) == 0 {
+		return ""
+	}
+	switch {
+	case records[0]["message"] != nil:
+		return "alerts"
+	case records[0]["kp_index"] != nil:
+		return "kp"
+	case records[0]["bz_gsm"] != nil:
+		return "bz"
+	case strings.HasSuffix(string(records[0]["energy"]), `nm"`):
+		return "xrays"
+	case string(records[0]["energy"]) == `">=2 MeV"`:
+		return "electrons"
+	case strings.HasSuffix(string(records[0]["energy"]), `MeV"`):
+		return "protons"
+	}
+	return ""
+}
+
+func indent(s, prefix string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
@@ -0,0 +1,145 @@
+// snmp.go
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// OIDs from mibs/SWPC-ALERTS-MIB.txt (netSnmpPlaypen.7)
+const (
+	swpcAlertsMIBOID        = "1.3.6.1.4.1.8072.9999.9999.7"
+	swpcAlertNotificationID = swpcAlertsMIBOID + ".0.1"
+	swpcAlertRuleOID        = swpcAlertsMIBOID + ".1.1.0"
+	swpcAlertSeverityOID    = swpcAlertsMIBOID + ".1.2.0"
+	swpcAlertTextOID        = swpcAlertsMIBOID + ".1.3.0"
+	swpcAlertTimeOID        = swpcAlertsMIBOID + ".1.4.0"
+
+	sysUpTimeOID    = "1.3.6.1.2.1.1.3.0"
+	snmpTrapOIDOID  = "1.3.6.1.6.3.1.1.4.1.0"
+	snmpTextMaxSize = 1024
+)
+
+var startTime = time.Now()
+
+// snmpNotifier sends each alert as an SNMPv2c trap
+type snmpNotifier struct{}
+
+func (snmpNotifier) Name() string { return "snmp" }
+
+func (snmpNotifier) Render(n Notification) string {
+	text := n.Text
+	if len(text) > snmpTextMaxSize {
+		text = text[:snmpTextMaxSize]
+		for !utf8.ValidString(text) {
+			text = text[:len(text)-1]
+		}
+	}
+	return text
+}
+
+func (s snmpNotifier) Send(n Notification) error {
+	target := config.SNMPTrapTarget
+	if !strings.Contains(target, ":") {
+		target += ":162"
+	}
+	conn, err := net.Dial("udp", target)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var alertTime int64
+	if !n.Time.IsZero() {
+		alertTime = n.Time.Unix()
+	}
+	varbinds := berSequence(
+		berVarbind(sysUpTimeOID, berTLV(0x43, berUint(uint64(time.Since(startTime)/(10*time.Millisecond))))),
+		berVarbind(snmpTrapOIDOID, berOID(swpcAlertNotificationID)),
+		berVarbind(swpcAlertRuleOID, berString(n.Rule)),
+		berVarbind(swpcAlertSeverityOID, berInt(int64(n.Severity))),
+		berVarbind(swpcAlertTextOID, berString(s.Render(n))),
+		berVarbind(swpcAlertTimeOID, berInt(alertTime)),
+	)
+	pdu := berTLV(0xa7, bytes.Join([][]byte{
+		berInt(int64(rand.Int31())), // request-id
+		berInt(0),                   // error-status
+		berInt(0),                   // error-index
+		varbinds,
+	}, nil))
+	packet := berSequence(berInt(1), berString(config.SNMPCommunity), pdu)
+	_, err = conn.Write(packet)
+	return err
+}
+
+// Minimal BER encoding, enough for a v2c trap
+
+func berTLV(tag byte, value []byte) []byte {
+	out := []byte{tag}
+	if n := len(value); n < 0x80 {
+		out = append(out, byte(n))
+	} else {
+		var length []byte
+		for ; n > 0; n >>= 8 {
+			length = append([]byte{byte(n)}, length...)
+		}
+		out = append(out, 0x80|byte(len(length)))
+		out = append(out, length...)
+	}
+	return append(out, value...)
+}
+
+func berSequence(items ...[]byte) []byte {
+	return berTLV(0x30, bytes.Join(items, nil))
+}
+
+func berInt(v int64) []byte {
+	b := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		b = append([]byte{byte(v)}, b...)
+	}
+	return berTLV(0x02, b)
+}
+
+// berUint encodes the content octets of an unsigned value (Counter, TimeTicks)
+func berUint(v uint64) []byte {
+	b := []byte{byte(v)}
+	for v >>= 8; v > 0; v >>= 8 {
+		b = append([]byte{byte(v)}, b...)
+	}
+	if b[0]&0x80 != 0 {
+		b = append([]byte{0}, b...)
+	}
+	return b
+}
+
+func berString(s string) []byte {
+	return berTLV(0x04, []byte(s))
+}
+
+func berOID(oid string) []byte {
+	parts := strings.Split(oid, ".")
+	arcs := make([]uint64, len(parts))
+	for i, p := range parts {
+		arcs[i], _ = strconv.ParseUint(p, 10, 64)
+	}
+	out := []byte{byte(arcs[0]*40 + arcs[1])}
+	for _, arc := range arcs[2:] {
+		enc := []byte{byte(arc & 0x7f)}
+		for arc >>= 7; arc > 0; arc >>= 7 {
+			enc = append([]byte{byte(arc&0x7f) | 0x80}, enc...)
+		}
+		out = append(out, enc...)
+	}
+	return berTLV(0x06, out)
+}
+
+func berVarbind(oid string, value []byte) []byte {
+	return berSequence(berOID(oid), value)
+}
@@ -0,0 +1,192 @@
+// source_health.go
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SourceHealth tracks fetch outcomes for one upstream data source
+type SourceHealth struct {
+	Fetches       int            `json:"fetches"`
+	LatencySum    float64        `json:"latency_sum_seconds"`
+	LastLatency   float64        `json:"last_latency_seconds"`
+	StatusCounts  map[string]int `json:"status_counts"`
+	ParseErrors   int            `json:"parse_errors"`
+	LastFetch     time.Time      `json:"last_fetch"`
+	LastSuccess   time.Time      `json:"last_success"`
+	LastError     string         `json:"last_error,omitempty"`
+	NewestTimeTag time.Time      `json:"newest_time_tag"`
+}
+
+// HealthSnapshot is what the poll loop persists for the status command
+type HealthSnapshot struct {
+	UpdatedAt time.Time                `json:"updated_at"`
+	Sources   map[string]*SourceHealth `json:"sources"`
+}
+
+var (
+	healthMu sync.Mutex
+	health   = make(map[string]*SourceHealth)
+)
+
+func sourceHealth(source string) *SourceHealth {
+	h, ok := health[source]
+	if !ok {
+		h = &SourceHealth{StatusCounts: make(map[string]int)}
+		health[source] = h
+	}
+	return h
+}
+
+// recordFetch notes one fetch attempt; status is the HTTP status code, or
+// "error" when no response was received
+func recordFetch(source, status string, latency time.Duration, parseErr bool, err error) {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	h := sourceHealth(source)
+	h.Fetches++
+	h.LastLatency = latency.Seconds()
+	h.LatencySum += latency.Seconds()
+	h.StatusCounts[status]++
+	h.LastFetch = time.Now().UTC()
+	if parseErr {
+		h.ParseErrors++
+	}
+	if err != nil {
+		h.LastError = err.Error()
+		writeEvent(eventError, eventIDFetchError, fmt.Sprintf("Fetching %s failed: %v", source, err))
+	} else {
+		h.LastSuccess = h.LastFetch
+		h.LastError = ""
+	}
+}
+
+// recordTimeTag notes the newest observation time seen from a source
+func recordTimeTag(source, timeTag string) {
+	t, err := parseTimeTag(timeTag)
+	if err != nil {
+		return
+	}
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	h := sourceHealth(source)
+	if t.After(h.NewestTimeTag) {
+		h.NewestTimeTag = t
+	}
+}
+
+func healthSnapshot() HealthSnapshot {
+	healthMu.Lock()
+	defer healthMu.Unlock()
+	snap := HealthSnapshot{UpdatedAt: time.Now().UTC(), Sources: make(map[string]*SourceHealth)}
+	for name, h := range health {
+		c := *h
+		c.StatusCounts = make(map[string]int)
+		for k, v := range h.StatusCounts {
+			c.StatusCounts[k] = v
+		}
+		snap.Sources[name] = &c
+	}
+	return snap
+}
+
+// parseTimeTag accepts the timestamp layouts used across SWPC and HAPI products
+func parseTimeTag(s string) (time.Time, error) {
+	layouts := []string{
+		time.RFC3339,
+		"2006-01-02T15:04:05",
+		"2006-01-02 15:04:05.000",
+		"2006-01-02 15:04:05",
+		"2006-01-02T15:04:05.000",
+		"2006-01-02T15:04Z",
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized time tag %q", s)
+}
+
+func sortedSources(snap HealthSnapshot) []string {
+	names := make([]string, 0, len(snap.Sources))
+	for name := range snap.Sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// writePrometheusMetrics renders the health data in the text exposition format
+func writePrometheusMetrics(w io.Writer) {
+	snap := healthSnapshot()
+	names := sortedSources(snap)
+	now := time.Now().UTC()
+
+	fmt.Fprintln(w, "# HELP swpc_fetch_duration_seconds Time taken to fetch and decode a source.")
+	fmt.Fprintln(w, "# TYPE swpc_fetch_duration_seconds summary")
+	for _, name := range names {
+		h := snap.Sources[name]
+		fmt.Fprintf(w, "swpc_fetch_duration_seconds_sum{source=%q} %g\n", name, h.LatencySum)
+		fmt.Fprintf(w, "swpc_fetch_duration_seconds_count{source=%q} %d\n", name, h.Fetches)
+	}
+	fmt.Fprintln(w, "# HELP swpc_fetch_responses_total Fetch results by HTTP status code.")
+	fmt.Fprintln(w, "# TYPE swpc_fetch_responses_total counter")
+	for _, name := range names {
+		h := snap.Sources[name]
+		codes := make([]string, 0, len(h.StatusCounts))
+		for code := range h.StatusCounts {
+			codes = append(codes, code)
+		}
+		sort.Strings(codes)
+		for _, code := range codes {
+			fmt.Fprintf(w, "swpc_fetch_responses_total{source=%q,code=%q} %d\n", name, code, h.StatusCounts[code])
+		}
+	}
+	fmt.Fprintln(w, "# HELP swpc_fetch_parse_errors_total Responses that could not be decoded.")
+	fmt.Fprintln(w, "# TYPE swpc_fetch_parse_errors_total counter")
+	for _, name := range names {
+		fmt.Fprintf(w, "swpc_fetch_parse_errors_total{source=%q} %d\n", name, snap.Sources[name].ParseErrors)
+	}
+	fmt.Fprintln(w, "# HELP swpc_last_success_timestamp_seconds Unix time of the last successful fetch.")
+	fmt.Fprintln(w, "# TYPE swpc_last_success_timestamp_seconds gauge")
+	for _, name := range names {
+		if t := snap.Sources[name].LastSuccess; !t.IsZero() {
+			fmt.Fprintf(w, "swpc_last_success_timestamp_seconds{source=%q} %d\n", name, t.Unix())
+		}
+	}
+	fmt.Fprintln(w, "# HELP swpc_data_age_seconds Age of the newest time_tag returned by a source.")
+	fmt.Fprintln(w, "# TYPE swpc_data_age_seconds gauge")
+	for _, name := range names {
+		if t := snap.Sources[name].NewestTimeTag; !t.IsZero() {
+			fmt.Fprintf(w, "swpc_data_age_seconds{source=%q} %s\n", name, strconv.FormatFloat(now.Sub(t).Seconds(), 'f', 0, 64))
+		}
+	}
+	if notifications == nil {
+		return
+	}
+	channels := notifications.channelHealth()
+	names = make([]string, 0, len(channels))
+	for name := range channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Fprintln(w, "# HELP swpc_notifications_total Notifications by channel and outcome; dropped ones found the queue full.")
+	fmt.Fprintln(w, "# TYPE swpc_notifications_total counter")
+	for _, name := range names {
+		h := channels[name]
+		fmt.Fprintf(w, "swpc_notifications_total{channel=%q,result=\"sent\"} %d\n", name, h.Sent)
+		fmt.Fprintf(w, "swpc_notifications_total{channel=%q,result=\"failed\"} %d\n", name, h.Failed)
+		fmt.Fprintf(w, "swpc_notifications_total{channel=%q,result=\"dropped\"} %d\n", name, h.Dropped)
+	}
+	fmt.Fprintln(w, "# HELP swpc_notification_queue_length Notifications waiting to be sent.")
+	fmt.Fprintln(w, "# TYPE swpc_notification_queue_length gauge")
+	for _, name := range names {
+		fmt.Fprintf(w, "swpc_notification_queue_length{channel=%q} %d\n", name, channels[name].Queued)
+	}
+}
@@ -0,0 +1,348 @@
+// sources.go
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	rtswMagURL = "https://services.swpc.noaa.gov/json/rtsw/rtsw_mag_1m.json"
+	aceMagURL  = "https://services.swpc.noaa.gov/text/ace-magnetometer.txt"
+)
+
+// Sample is one observation of a metric
+type Sample struct {
+	TimeTag string  `json:"time_tag"`
+	Value   float64 `json:"value"`
+}
+
+// Provider fetches the time series for one metric from one upstream source,
+// oldest sample first
+type Provider struct {
+	Metric string
+	Fetch  func() ([]Sample, error)
+	// MaxAge overrides source_max_age_minutes for products that update
+	// less often than the others; 0 uses the global setting
+	MaxAge time.Duration
+}
+
+// ProviderConfig defines an extra provider: a JSON array of objects (e.g. a
+// mirror of an SWPC product) or one parameter of a HAPI dataset (e.g. on the
+// ESA Space Weather Service Network)
+type ProviderConfig struct {
+	Metric     string            `json:"metric" desc:"Metric this provider supplies (kp, bz, ...)"`
+	Type       string            `json:"type" desc:"How to read the provider" enum:"json,hapi"`
+	URL        string            `json:"url" desc:"URL returning a JSON array of objects, or the HAPI server base URL"`
+	TimeField  string            `json:"time_field" desc:"json: field holding the timestamp; default time_tag"`
+	ValueField string            `json:"value_field" desc:"json: field holding the value"`
+	Dataset    string            `json:"dataset" desc:"hapi: dataset ID"`
+	Parameter  string            `json:"parameter" desc:"hapi: parameter holding the value"`
+	Headers    map[string]string `json:"headers" desc:"Extra request headers, e.g. for authentication"`
+	MaxAge     int               `json:"max_age_minutes" desc:"Treat this provider as stale after this long instead of source_max_age_minutes"`
+}
+
+var builtinProviders = map[string]Provider{
+	"planetary_k_index": {Metric: "kp", Fetch: jsonSeries("planetary_k_index", kpIndexURL, "time_tag", "kp_index")},
+	"dscovr_solar_wind": {Metric: "bz", Fetch: jsonSeries("dscovr_solar_wind", bzFieldURL, "time_tag", "bz_gsm")},
+	"rtsw_mag":          {Metric: "bz", Fetch: jsonSeries("rtsw_mag", rtswMagURL, "time_tag", "bz_gsm")},
+	"ace_mag":           {Metric: "bz", Fetch: fetchACEMag},
+	"bom_k_index":       {Metric: "kp", Fetch: fetchBoMKIndex},
+	"gfz_kp":            {Metric: "kp", Fetch: gfzSeries("gfz_kp", "Kp", 3*time.Hour), MaxAge: 4 * time.Hour},
+	"gfz_hp30":          {Metric: "kp", Fetch: gfzSeries("gfz_hp30", "Hp30", 30*time.Minute), MaxAge: 90 * time.Minute},
+}
+
+func lookupProvider(name string) (Provider, bool) {
+	if pc, ok := config.Providers[name]; ok {
+		maxAge := time.Duration(pc.MaxAge) * time.Minute
+		if pc.Type == "hapi" {
+			return Provider{Metric: pc.Metric, Fetch: hapiSeries(name, pc), MaxAge: maxAge}, true
+		}
+		timeField := pc.TimeField
+		if timeField == "" {
+			timeField = "time_tag"
+		}
+		return Provider{Metric: pc.Metric, Fetch: jsonSeriesWithHeaders(name, pc.URL, timeField, pc.ValueField, pc.Headers), MaxAge: maxAge}, true
+	}
+	p, ok := builtinProviders[name]
+	return p, ok
+}
+
+// fetchMetric tries the providers configured for metric in order and
+// returns the first fresh series with the name of the provider that
+// produced it, or with a max or mean source_strategy, every fresh series
+// blended. If every provider is failing or stale, the freshest stale series
+// is used rather than nothing.
+func fetchMetric(metric string) ([]Sample, string, error) {
+	maxAge := time.Duration(config.SourceMaxAge) * time.Minute
+	strategy := config.SourceStrategy[metric]
+	var fresh []namedSeries
+	var stale []Sample
+	var staleSource string
+	var staleTime time.Time
+	var errs []string
+	for _, name := range config.Sources[metric] {
+		p, ok := lookupProvider(name)
+		if !ok || p.Metric != metric {
+			errs = append(errs, fmt.Sprintf("%s: no such %s provider", name, metric))
+			continue
+		}
+		samples, err := p.Fetch()
+		if err == nil && len(samples) == 0 {
+			err = fmt.Errorf("no data")
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		age := maxAge
+		if p.MaxAge > 0 {
+			age = p.MaxAge
+		}
+		newest, err := parseTimeTag(samples[len(samples)-1].TimeTag)
+		if err != nil || age <= 0 || time.Since(newest) <= age {
+			if strategy == "" || strategy == "failover" {
+				return samples, name, nil
+			}
+			fresh = append(fresh, namedSeries{name, samples})
+			continue
+		}
+		errs = append(errs, fmt.Sprintf("%s: stale since %s", name, newest.Format(time.RFC3339)))
+		if stale == nil || newest.After(staleTime) {
+			stale, staleSource, staleTime = samples, name, newest
+		}
+	}
+	if len(fresh) > 0 {
+		samples, name := blendSeries(metric, strategy, fresh)
+		return samples, name, nil
+	}
+	if stale != nil {
+		log.Printf("All %s sources stale, using %s: %s", metric, staleSource, strings.Join(errs, "; "))
+		return stale, staleSource, nil
+	}
+	if len(errs) == 0 {
+		return nil, "", fmt.Errorf("no sources configured for %s", metric)
+	}
+	return nil, "", fmt.Errorf("all %s sources failed: %s", metric, strings.Join(errs, "; "))
+}
+
+type namedSeries struct {
+	name    string
+	samples []Sample
+}
+
+var sourceStrategies = []string{"failover", "max", "mean"}
+
+// sourceLowIsSevere lists metrics where lower values are worse, so the max
+// strategy takes the most severe reading rather than the largest number
+var sourceLowIsSevere = map[string]bool{"bz": true}
+
+// sourceDisagreement is how far apart the latest values of fresh providers
+// may be before a blend logs that they disagree
+var sourceDisagreement = map[string]float64{"kp": 1, "bz": 5}
+
+// blendSeries combines the latest values of several fresh series by max,
+// the most severe of them (the lowest for Bz), or mean. The first series supplies the history and the time tag, so only its
+// newest value is replaced; the source is named after the strategy and its
+// providers, e.g. max(planetary_k_index,gfz_hp30).
+func blendSeries(metric, strategy string, fresh []namedSeries) ([]Sample, string) {
+	if len(fresh) == 1 {
+		return fresh[0].samples, fresh[0].name
+	}
+	var names, values []string
+	lo, hi, sum := math.Inf(1), math.Inf(-1), 0.0
+	for _, s := range fresh {
+		v := s.samples[len(s.samples)-1].Value
+		names = append(names, s.name)
+		values = append(values, fmt.Sprintf("%s %.2f", s.name, v))
+		lo, hi, sum = math.Min(lo, v), math.Max(hi, v), sum+v
+	}
+	if tolerance, ok := sourceDisagreement[metric]; ok && hi-lo >= tolerance {
+		log.Printf("%s sources disagree: %s", metric, strings.Join(values, ", "))
+	}
+	blended := hi
+	if sourceLowIsSevere[metric] {
+		blended = lo
+	}
+	if strategy == "mean" {
+		blended = sum / float64(len(fresh))
+	}
+	samples := append([]Sample(nil), fresh[0].samples...)
+	samples[len(samples)-1].Value = blended
+	return samples, strategy + "(" + strings.Join(names, ",") + ")"
+}
+
+// checkSourceStrategies reports the first unknown source_strategy
+func checkSourceStrategies() error {
+	for metric, strategy := range config.SourceStrategy {
+		if !subscribed(sourceStrategies, strategy) {
+			return fmt.Errorf("source_strategy.%s: unknown strategy %q (want %s)", metric, strategy, strings.Join(sourceStrategies, ", "))
+		}
+	}
+	return nil
+}
+
+// jsonSeries returns a fetcher for a JSON array of objects, taking the time
+// and value from the named fields. Values may be numbers or numeric strings;
+// records without a usable value are skipped.
+func jsonSeries(source, url, timeField, valueField string) func() ([]Sample, error) {
+	return jsonSeriesWithHeaders(source, url, timeField, valueField, nil)
+}
+
+func jsonSeriesWithHeaders(source, url, timeField, valueField string, headers map[string]string) func() ([]Sample, error) {
+	return func() ([]Sample, error) {
+		req, err := newProviderRequest(url, headers)
+		if err != nil {
+			return nil, err
+		}
+		var records []map[string]interface{}
+		err = fetchRequest(source, req, func(body io.Reader) error {
+			return json.NewDecoder(body).Decode(&records)
+		})
+		if err != nil {
+			return nil, err
+		}
+		samples := seriesFromRecords(records, timeField, valueField)
+		if len(samples) > 0 {
+			recordTimeTag(source, samples[len(samples)-1].TimeTag)
+		}
+		return samples, nil
+	}
+}
+
+func seriesFromRecords(records []map[string]interface{}, timeField, valueField string) []Sample {
+	var samples []Sample
+	for _, r := range records {
+		t, _ := r[timeField].(string)
+		var v float64
+		switch x := r[valueField].(type) {
+		case float64:
+			v = x
+		case string:
+			f, err := strconv.ParseFloat(x, 64)
+			if err != nil {
+				continue
+			}
+			v = f
+		default:
+			continue
+		}
+		samples = append(samples, Sample{TimeTag: t, Value: v})
+	}
+	return samples
+}
+
+func newProviderRequest(url string, headers map[string]string) (*http.Request, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+// decodeSeries parses a saved product file the same way jsonSeries does
+func decodeSeries(data []byte, timeField, valueField string) ([]Sample, error) {
+	var records []map[string]interface{}
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return seriesFromRecords(records, timeField, valueField), nil
+}
+
+// fetchACEMag reads the ACE magnetometer text product:
+// YR MO DA HHMM MJD SOD S Bx By Bz Bt Lat Long, where S != 0 marks bad data
+func fetchACEMag() ([]Sample, error) {
+	var samples []Sample
+	err := fetchAndDecode("ace_mag", aceMagURL, func(body io.Reader) error {
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "#") || strings.HasPrefix(line, ":") {
+				continue
+			}
+			f := strings.Fields(line)
+			if len(f) < 13 || f[6] != "0" {
+				continue
+			}
+			bz, err := strconv.ParseFloat(f[9], 64)
+			if err != nil || bz <= -999 {
+				continue
+			}
+			if len(f[3]) != 4 {
+				continue
+			}
+			timeTag := fmt.Sprintf("%s-%s-%sT%s:%s:00", f[0], f[1], f[2], f[3][:2], f[3][2:])
+			samples = append(samples, Sample{TimeTag: timeTag, Value: bz})
+		}
+		return scanner.Err()
+	})
+	if len(samples) > 0 {
+		recordTimeTag("ace_mag", samples[len(samples)-1].TimeTag)
+	}
+	return samples, err
+}
+
+// hapiSeries returns a fetcher for one parameter of a dataset on a HAPI
+// server (https://github.com/hapi-server/data-specification), covering the
+// last 24 hours. CSV is used since every HAPI server must support it.
+func hapiSeries(source string, pc ProviderConfig) func() ([]Sample, error) {
+	return func() ([]Sample, error) {
+		now := time.Now().UTC()
+		q := url.Values{}
+		q.Set("id", pc.Dataset)
+		q.Set("parameters", pc.Parameter)
+		q.Set("time.min", now.Add(-24*time.Hour).Format("2006-01-02T15:04:05Z"))
+		q.Set("time.max", now.Format("2006-01-02T15:04:05Z"))
+		q.Set("format", "csv")
+		req, err := newProviderRequest(strings.TrimRight(pc.URL, "/")+"/data?"+q.Encode(), pc.Headers)
+		if err != nil {
+			return nil, err
+		}
+		var samples []Sample
+		err = fetchRequest(source, req, func(body io.Reader) error {
+			r := csv.NewReader(body)
+			r.FieldsPerRecord = -1
+			r.Comment = '#'
+			rows, err := r.ReadAll()
+			if err != nil {
+				return err
+			}
+			samples = hapiSamples(rows)
+			return nil
+		})
+		if len(samples) > 0 {
+			recordTimeTag(source, samples[len(samples)-1].TimeTag)
+		}
+		return samples, err
+	}
+}
+
+// hapiSamples takes time and value from the first two columns, skipping
+// fill values and anything unparseable
+func hapiSamples(rows [][]string) []Sample {
+	var samples []Sample
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(row[1]), 64)
+		if err != nil || math.IsNaN(v) || v <= -1e30 || v >= 1e30 {
+			continue
+		}
+		samples = append(samples, Sample{TimeTag: strings.TrimSpace(row[0]), Value: v})
+	}
+	return samples
+}
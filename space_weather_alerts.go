@@ -5,11 +5,15 @@ import (
 	"crypto/sha256"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	twilio "github.com/twilio/twilio-go"
@@ -18,28 +22,155 @@ import (
 
 const alertCacheFile = ".swpc-alert-cache.json"
 
-// Config holds runtime configuration values
+const (
+	swpcAlertsURL = "https://services.swpc.noaa.gov/json/alerts.json"
+	kpIndexURL    = "https://services.swpc.noaa.gov/json/planetary_k_index_1m.json"
+	bzFieldURL    = "https://services.swpc.noaa.gov/products/summary/dscovr-solar-wind.json"
+)
+
+// Config holds runtime configuration values. The desc and enum tags feed
+// the `config schema` command.
 type Config struct {
-	TwilioSID      string  `json:"twilio_sid"`
-	TwilioAuth     string  `json:"twilio_auth"`
-	TwilioFrom     string  `json:"twilio_from"`
-	TwilioTo       string  `json:"twilio_to"`
-	DryRun         bool    `json:"dry_run"`
-	CheckInterval  int     `json:"check_interval_minutes"`
-	KpThreshold    float64 `json:"kp_threshold"`
-	BzThreshold    float64 `json:"bz_threshold"`
-	ProtonFluxThreshold float64 `json:"proton_flux_threshold"`
-	XrayFluxThreshold float64 `json:"xray_flux_threshold"`
+	TwilioSID            string                    `json:"twilio_sid" desc:"Twilio account SID"`
+	TwilioAuth           string                    `json:"twilio_auth" desc:"Twilio auth token"`
+	TwilioFrom           string                    `json:"twilio_from" desc:"Sending number in E.164 format"`
+	TwilioTo             string                    `json:"twilio_to" desc:"Recipient number in E.164 format"`
+	SMSRecipients        []SMSRecipient            `json:"sms_recipients" desc:"More numbers to text, each with its own categories"`
+	DryRun               bool                      `json:"dry_run" desc:"Log messages instead of sending them"`
+	CheckInterval        int                       `json:"check_interval_minutes" desc:"Minutes between polls"`
+	KpThreshold          float64                   `json:"kp_threshold" desc:"Alert when planetary Kp is at or above this value"`
+	BzThreshold          float64                   `json:"bz_threshold" desc:"Alert when IMF Bz (nT) drops below this value"`
+	KpCriticalThreshold  float64                   `json:"kp_critical_threshold" desc:"Kp at or above this is critical rather than a warning"`
+	BzCriticalThreshold  float64                   `json:"bz_critical_threshold" desc:"Bz (nT) below this is critical rather than a warning"`
+	ThresholdSchedules   []ThresholdSchedule       `json:"threshold_schedules" desc:"Different Kp/Bz thresholds by local time of day; the first matching window wins"`
+	Timezone             string                    `json:"timezone" desc:"IANA timezone for threshold_schedules, e.g. Europe/Oslo; system zone when empty"`
+	BzFlipDelta          float64                   `json:"bz_flip_delta_nt" desc:"Alert when Bz swings from northward to southward by at least this many nT, e.g. 15; 0, the default, disables"`
+	BzFlipWindow         int                       `json:"bz_flip_window_minutes" desc:"How quickly the Bz swing must happen"`
+	RecurrenceKp         float64                   `json:"recurrence_kp" desc:"Warn ahead of the 27-day repeat of days whose Kp reached this, e.g. 6; 0, the default, disables"`
+	RecurrenceLeadDays   int                       `json:"recurrence_lead_days" desc:"How many days before a 27-day repeat to warn"`
+	SIObservatory        string                    `json:"sudden_impulse_observatory" desc:"USGS magnetometer (e.g. BOU, FRD, CMO) to watch for sudden impulses; disabled when empty"`
+	SIThreshold          float64                   `json:"sudden_impulse_nt_per_min" desc:"One-minute change in H (nT) that counts as a sudden impulse; twice this is critical"`
+	CACTus               bool                      `json:"cactus" desc:"Alert on halo CMEs from the CACTus automated LASCO catalog"`
+	CACTusURL            string                    `json:"cactus_url" desc:"CACTus catalog (cmecat.txt) to read"`
+	CMEMinSpeed          float64                   `json:"cme_min_speed" desc:"Ignore CMEs slower than this (km/s)"`
+	ProtonFluxThreshold  float64                   `json:"proton_flux_threshold" desc:"Proton flux threshold (pfu)"`
+	SEPHardFlux          float64                   `json:"sep_hard_flux" desc:"Alert on hard-spectrum proton events once >=100 MeV flux reaches this (pfu), e.g. 1; 0, the default, disables"`
+	SEPHardRatio         float64                   `json:"sep_hard_ratio" desc:"Minimum >=100 MeV to >=10 MeV flux ratio for a hard spectrum"`
+	XrayFluxThreshold    float64                   `json:"xray_flux_threshold" desc:"X-ray flux threshold (W/m^2)"`
+	FlareOnsetFlux       float64                   `json:"flare_onset_flux" desc:"Send a flare onset alert when a rising X-ray flux reaches this (W/m^2; 1e-5 is M1); 0, the default, disables"`
+	FluenceThreshold     float64                   `json:"electron_fluence_threshold" desc:"Warn when the daily >2 MeV electron fluence reaches this (electrons/cm^2/sr), raising internal charging risk, e.g. 1e9; 0, the default, disables"`
+	FluenceCritical      float64                   `json:"electron_fluence_critical_threshold" desc:"Daily >2 MeV electron fluence that is critical; 0 disables"`
+	StateBackend         string                    `json:"state_backend" desc:"Where dedup state is kept" enum:"file,redis,s3,gcs"`
+	RedisAddr            string                    `json:"redis_addr" desc:"Redis host:port"`
+	RedisPassword        string                    `json:"redis_password" desc:"Redis AUTH password"`
+	RedisDB              int                       `json:"redis_db" desc:"Redis database number"`
+	RedisKeyPrefix       string                    `json:"redis_key_prefix" desc:"Prefix for all Redis keys"`
+	RedisClaimTTLDays    int                       `json:"redis_claim_ttl_days" desc:"Days a sent-alert claim is kept in Redis before it expires; 0 keeps it forever"`
+	StateBucket          string                    `json:"state_bucket" desc:"Bucket name for the s3 and gcs backends"`
+	StateBucketPrefix    string                    `json:"state_bucket_prefix" desc:"Object key prefix inside the bucket"`
+	StateBucketRegion    string                    `json:"state_bucket_region" desc:"Bucket region (us-east-1 for s3, auto for gcs when empty)"`
+	StateBucketEndpoint  string                    `json:"state_bucket_endpoint" desc:"S3-compatible endpoint URL; derived from the backend when empty"`
+	StateBucketAccessKey string                    `json:"state_bucket_access_key" desc:"Access key or GCS HMAC key ID; falls back to AWS_ACCESS_KEY_ID"`
+	StateBucketSecretKey string                    `json:"state_bucket_secret_key" desc:"Secret key or GCS HMAC secret; falls back to AWS_SECRET_ACCESS_KEY"`
+	HTTPListen           string                    `json:"http_listen" desc:"Address for the monitoring HTTP server (e.g. :9090); disabled when empty"`
+	HTTPToken            string                    `json:"http_token" desc:"Bearer token for the /api sensor endpoints; they are disabled when empty"`
+	Latitude             float64                   `json:"latitude" desc:"Your latitude, used for aurora probability"`
+	Longitude            float64                   `json:"longitude" desc:"Your longitude, used for aurora probability"`
+	NotifyWorkers        int                       `json:"notify_workers" desc:"Delivery workers per notification channel"`
+	NotifyQueueSize      int                       `json:"notify_queue_size" desc:"Notifications buffered per channel before new ones are dropped"`
+	NotifyTimeout        int                       `json:"notify_timeout_seconds" desc:"Give up waiting on a single delivery after this many seconds"`
+	Templates            map[string]string         `json:"templates" desc:"Go text/template overrides per rule (see the render command for the list)"`
+	Subscriptions        map[string][]string       `json:"subscriptions" desc:"Categories each channel (sms, snmp, eventlog, sms:<number>) receives; all when absent"`
+	LogFile              string                    `json:"log_file" desc:"Also write logs to this file; stderr only when empty"`
+	LogMaxSizeMB         int                       `json:"log_max_size_mb" desc:"Rotate the log file once it exceeds this size"`
+	LogRotateHours       int                       `json:"log_rotate_hours" desc:"Rotate the log file once it is this old; 0 disables"`
+	LogMaxBackups        int                       `json:"log_max_backups" desc:"Rotated log files to keep; 0 keeps all"`
+	LogMaxAgeDays        int                       `json:"log_max_age_days" desc:"Delete rotated log files older than this; 0 keeps all"`
+	JSONLOutput          string                    `json:"jsonl_output" desc:"Write every reading and alert decision as JSON Lines to this file, or - for stdout; disabled when empty"`
+	ZabbixServer         string                    `json:"zabbix_server" desc:"Zabbix server or proxy host[:port] to push readings to; disabled when empty"`
+	ZabbixHost           string                    `json:"zabbix_host" desc:"Host name the items belong to in Zabbix"`
+	ZabbixKeys           map[string]string         `json:"zabbix_keys" desc:"Trapper item key per metric (kp, bz, xray_flux, proton_flux, ...); defaults to swpc.<metric>"`
+	SNMPTrapTarget       string                    `json:"snmp_trap_target" desc:"host[:port] to send SNMPv2c traps to; disabled when empty"`
+	SNMPCommunity        string                    `json:"snmp_community" desc:"SNMP community string for traps"`
+	WindowsEventLog      bool                      `json:"windows_event_log" desc:"On Windows, also write alerts and errors to the Event Log"`
+	WindowsEventSource   string                    `json:"windows_event_source" desc:"Event Log source name"`
+	MQTTBroker           string                    `json:"mqtt_broker" desc:"MQTT broker URL (tcp://host:1883 or ssl://host:8883) for remote control; disabled when empty"`
+	MQTTUsername         string                    `json:"mqtt_username" desc:"MQTT username"`
+	MQTTPassword         string                    `json:"mqtt_password" desc:"MQTT password"`
+	MQTTClientID         string                    `json:"mqtt_client_id" desc:"MQTT client ID; must be unique per instance"`
+	MQTTControlTopic     string                    `json:"mqtt_control_topic" desc:"Topic accepting pause/resume/mute/unmute/test commands"`
+	Sources              map[string][]string       `json:"sources" desc:"Providers to try in order per metric (kp, bz); the first fresh one wins"`
+	Providers            map[string]ProviderConfig `json:"providers" desc:"Extra JSON providers, e.g. mirrors, usable in sources"`
+	SourceMaxAge         int                       `json:"source_max_age_minutes" desc:"Fail over when a provider's newest sample is older than this; 0 disables"`
+	SourceStrategy       map[string]string         `json:"source_strategy" desc:"How to use the fresh providers per metric: failover (the first one, default), max (the most severe; the lowest for bz) or mean of them all"`
+	MetOffice            bool                      `json:"metoffice" desc:"Also check the Met Office (MOSWOC) space weather forecast and alerts"`
+	MetOfficeURL         string                    `json:"metoffice_url" desc:"Met Office page or feed to read"`
+	MetOfficeAPIKey      string                    `json:"metoffice_api_key" desc:"Sent as the apikey header, for Met Office DataHub feeds"`
+	BoMAPIKey            string                    `json:"bom_api_key" desc:"Australian Space Weather Services API key; enables aurora alerts/watches and the bom_k_index provider"`
+	BoMLocation          string                    `json:"bom_location" desc:"Location for the bom_k_index provider (e.g. Australian region, Hobart, Canberra)"`
+	NICT                 bool                      `json:"nict" desc:"Also check the NICT (Japan) space weather forecast and warnings"`
+	NICTURL              string                    `json:"nict_url" desc:"NICT forecast page to read"`
+	IMAPServer           string                    `json:"imap_server" desc:"IMAP server host[:port] (TLS, default port 993) receiving SWPC product subscription emails; disabled when empty"`
+	IMAPUsername         string                    `json:"imap_username" desc:"IMAP username"`
+	IMAPPassword         string                    `json:"imap_password" desc:"IMAP password"`
+	IMAPMailbox          string                    `json:"imap_mailbox" desc:"Mailbox the SWPC emails are delivered to"`
+	IMAPSearch           string                    `json:"imap_search" desc:"IMAP SEARCH criteria selecting new SWPC emails; matches are flagged seen once read"`
 }
 
 var config Config
 
+func defaultConfig() Config {
+	return Config{
+		CheckInterval:       15,
+		KpThreshold:         7.0,
+		BzThreshold:         -8.0,
+		KpCriticalThreshold: 8.0,
+		BzCriticalThreshold: -15.0,
+		BzFlipWindow:        30,
+		RecurrenceLeadDays:  2,
+		SIThreshold:         10.0,
+		CACTusURL:           cactusURL,
+		CMEMinSpeed:         500,
+		ProtonFluxThreshold: 0.1,
+		SEPHardRatio:        0.1,
+		XrayFluxThreshold:   0.0001,
+		FluenceCritical:     1e10,
+		IMAPMailbox:         "INBOX",
+		IMAPSearch:          `UNSEEN FROM "noaa.gov"`,
+		StateBackend:        "file",
+		RedisAddr:           "localhost:6379",
+		RedisKeyPrefix:      "swpc:",
+		RedisClaimTTLDays:   30,
+		NotifyWorkers:       2,
+		NotifyQueueSize:     100,
+		NotifyTimeout:       30,
+		LogMaxSizeMB:        10,
+		LogRotateHours:      24,
+		LogMaxBackups:       7,
+		LogMaxAgeDays:       30,
+		ZabbixHost:          "space-weather",
+		SNMPCommunity:       "public",
+		WindowsEventSource:  "SpaceWeatherAlerts",
+		MQTTClientID:        "space-alerts",
+		MQTTControlTopic:    "swpc/cmd",
+		Sources: map[string][]string{
+			"kp": {"planetary_k_index"},
+			"bz": {"dscovr_solar_wind", "rtsw_mag", "ace_mag"},
+		},
+		SourceMaxAge: 30,
+		MetOfficeURL: metOfficeURL,
+		BoMLocation:  "Australian region",
+		NICTURL:      nictURL,
+	}
+}
+
 func loadConfig() {
 	defaultPath := os.ExpandEnv("$HOME/.config/swpc-alerts/config.json")
 	data, err := ioutil.ReadFile(defaultPath)
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	config = defaultConfig()
 	err = json.Unmarshal(data, &config)
 	if err != nil {
 		log.Fatalf("Failed to parse config: %v", err)
@@ -49,23 +180,14 @@ func loadConfig() {
 // Re-declare necessary types and utility functions
 
 type Alert struct {
-	Message string `json:"message"`
-}
-
-type KpIndex struct {
-	TimeTag string  `json:"time_tag"`
-	Kp      float64 `json:"kp_index"`
-}
-
-type BzReading struct {
-	Bz float64 `json:"bz_gsm"`
-	TimeTag string `json:"time_tag"`
+	Message       string `json:"message"`
+	IssueDatetime string `json:"issue_datetime"`
 }
 
 type FluxReading struct {
-	Energy string  `json:"energy"`
-	Flux   float64 `json:"flux"`
-	TimeTag string `json:"time_tag"`
+	Energy  string  `json:"energy"`
+	Flux    float64 `json:"flux"`
+	TimeTag string  `json:"time_tag"`
 }
 
 type AlertCache map[string]bool
@@ -90,7 +212,16 @@ func hashAlert(content string) string {
 }
 
 func sendSMS(body string) error {
+	return sendSMSTo(config.TwilioTo, body)
+}
+
+// sendSMSTo texts body to one number
+func sendSMSTo(to, body string) error {
 	if config.DryRun {
+		if to != config.TwilioTo {
+			log.Printf("[Dry Run] SMS to %s would be sent: %s", to, body)
+			return nil
+		}
 		log.Println("[Dry Run] SMS would be sent:", body)
 		return nil
 	}
@@ -101,7 +232,7 @@ func sendSMS(body string) error {
 	})
 
 	params := &openapi.CreateMessageParams{}
-	params.SetTo(config.TwilioTo)
+	params.SetTo(to)
 	params.SetFrom(config.TwilioFrom)
 	params.SetBody(body)
 
@@ -114,101 +245,316 @@ func sendSMS(body string) error {
 	return err
 }
 
-func fetchJSON(url string, target interface{}) error {
-	resp, err := http.Get(url)
+// fetchJSON decodes url into target, recording the outcome under source
+func fetchJSON(source, url string, target interface{}) error {
+	return fetchAndDecode(source, url, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(target)
+	})
+}
+
+// httpClient makes every feed and bucket request. Its timeout covers the
+// whole exchange, body included, so one hung server can't stall the poll.
+// The transport is left nil so --debug-http still sees the traffic.
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+// fetchAndDecode GETs url and hands the body to decode, recording the
+// outcome under source
+func fetchAndDecode(source, url string, decode func(io.Reader) error) error {
+	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
 	}
+	return fetchRequest(source, req, decode)
+}
+
+// fetchRequest is fetchAndDecode for requests that need headers or a body
+func fetchRequest(source string, req *http.Request, decode func(io.Reader) error) error {
+	url := req.URL.String()
+	start := time.Now()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		recordFetch(source, "error", time.Since(start), false, err)
+		return err
+	}
 	defer resp.Body.Close()
-	return json.NewDecoder(resp.Body).Decode(target)
+	status := strconv.Itoa(resp.StatusCode)
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s: %s", url, resp.Status)
+		recordFetch(source, status, time.Since(start), false, err)
+		return err
+	}
+	err = decode(resp.Body)
+	recordFetch(source, status, time.Since(start), err != nil, err)
+	return err
 }
 
-func processSWPCAlerts(cache AlertCache) {
+func processSWPCAlerts(store StateStore) (Severity, error) {
 	var alerts []Alert
-	err := fetchJSON("https://services.swpc.noaa.gov/json/alerts.json", &alerts)
+	err := fetchJSON("swpc_alerts", swpcAlertsURL, &alerts)
 	if err != nil {
 		log.Println("Error fetching SWPC alerts:", err)
-		return
+		return SeverityOK, err
 	}
+	for _, alert := range alerts {
+		recordTimeTag("swpc_alerts", alert.IssueDatetime)
+	}
+	notes := evaluateSWPCAlerts(alerts)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+func evaluateSWPCAlerts(alerts []Alert) []Notification {
+	var notes []Notification
 	for _, alert := range alerts {
 		msg := alert.Message
 		if strings.Contains(msg, "G3") || strings.Contains(msg, "G4") || strings.Contains(msg, "G5") ||
 			strings.Contains(msg, "S3") || strings.Contains(msg, "S4") || strings.Contains(msg, "S5") ||
 			strings.Contains(msg, "R3") || strings.Contains(msg, "R4") || strings.Contains(msg, "R5") {
 
-			hash := hashAlert(msg)
-			if !cache[hash] {
-				cache[hash] = true
-				text := fmt.Sprintf("🌐 SWPC Alert: %s", msg)
-				if err := sendSMS(text); err != nil {
-					log.Println("SMS failed:", err)
-				}
+			severity := SeverityWarning
+			if noaaScaleLevel(msg) >= 4 {
+				severity = SeverityCritical
 			}
+			issued, _ := parseTimeTag(alert.IssueDatetime)
+			notes = append(notes, Notification{
+				Key:      swpcAlertKey(msg),
+				OldKey:   hashAlert(msg),
+				Rule:     "swpc_alert",
+				Text:     renderMessage("swpc_alert", map[string]interface{}{"Message": msg}),
+				Summary:  swpcSummary(msg),
+				Severity: severity,
+				Time:     issued,
+			})
 		}
 	}
+	return notes
 }
 
-func processKpIndex(cache AlertCache) {
-	var kpList []KpIndex
-	err := fetchJSON("https://services.swpc.noaa.gov/json/planetary_k_index_1m.json", &kpList)
-	if err != nil || len(kpList) == 0 {
+func processKpIndex(store StateStore) (Severity, error) {
+	kpList, source, err := fetchMetric("kp")
+	if err != nil {
 		log.Println("Error fetching Kp index:", err)
-		return
+		return SeverityOK, err
 	}
 	latest := kpList[len(kpList)-1]
-	if latest.Kp >= config.KpThreshold {
-		msg := fmt.Sprintf("🧠 K-index Alert: Kp = %.2f at %s\nLinked to sleep disruption, anxiety, and focus issues.", latest.Kp, latest.TimeTag)
-		hash := hashAlert(msg)
-		if !cache[hash] {
-			cache[hash] = true
-			_ = sendSMS(msg)
-		}
+	recordReading("kp", source, latest.Value, latest.TimeTag)
+	notes := evaluateKpIndex(kpList)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+func evaluateKpIndex(kpList []Sample) []Notification {
+	latest := kpList[len(kpList)-1]
+	threshold, _ := thresholdsAt(time.Now())
+	if latest.Value < threshold {
+		return nil
 	}
+	severity := SeverityWarning
+	if latest.Value >= config.KpCriticalThreshold {
+		severity = SeverityCritical
+	}
+	observed, _ := parseTimeTag(latest.TimeTag)
+	lo, hi := range24h("kp", kpList)
+	msg := renderMessage("kp", map[string]interface{}{"Kp": latest.Value, "TimeTag": latest.TimeTag, "Min24h": lo, "Max24h": hi})
+	return []Notification{{Key: hashAlert(msg), Rule: "kp", Text: msg, Severity: severity, Time: observed}}
 }
 
-func processBzField(cache AlertCache) {
-	var bzList []BzReading
-	err := fetchJSON("https://services.swpc.noaa.gov/products/summary/dscovr-solar-wind.json", &bzList)
-	if err != nil || len(bzList) == 0 {
+func processBzField(store StateStore) (Severity, error) {
+	bzList, source, err := fetchMetric("bz")
+	if err != nil {
 		log.Println("Error fetching Bz field:", err)
-		return
+		return SeverityOK, err
 	}
 	latest := bzList[len(bzList)-1]
-	if latest.Bz < config.BzThreshold {
-		msg := fmt.Sprintf("🧠 Geomagnetic Instability Alert: Bz = %.2f nT at %s\nMay disrupt sleep, mood, or focus in sensitive individuals.", latest.Bz, latest.TimeTag)
-		hash := hashAlert(msg)
-		if !cache[hash] {
-			cache[hash] = true
-			_ = sendSMS(msg)
+	recordReading("bz", source, latest.Value, latest.TimeTag)
+	notes := append(evaluateBzField(bzList), evaluateBzFlip(bzList)...)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+func evaluateBzField(bzList []Sample) []Notification {
+	latest := bzList[len(bzList)-1]
+	_, threshold := thresholdsAt(time.Now())
+	if latest.Value >= threshold {
+		return nil
+	}
+	severity := SeverityWarning
+	if latest.Value < config.BzCriticalThreshold {
+		severity = SeverityCritical
+	}
+	observed, _ := parseTimeTag(latest.TimeTag)
+	lo, hi := range24h("bz", bzList)
+	msg := renderMessage("bz", map[string]interface{}{"Bz": latest.Value, "TimeTag": latest.TimeTag, "Min24h": lo, "Max24h": hi})
+	return []Notification{{Key: hashAlert(msg), Rule: "bz", Text: msg, Severity: severity, Time: observed}}
+}
+
+// poll runs every processor once and saves state
+func poll(store StateStore) pollResult {
+	var result pollResult
+	loadReadingHistory(store)
+	processors := []func(StateStore) (Severity, error){processSWPCAlerts, processKpIndex, processBzField}
+	if config.FlareOnsetFlux > 0 {
+		processors = append(processors, processFlareOnset)
+	}
+	if config.SEPHardFlux > 0 {
+		processors = append(processors, processSEPHardness)
+	}
+	if config.FluenceThreshold > 0 {
+		processors = append(processors, processElectronFluence)
+	}
+	if config.SIObservatory != "" {
+		processors = append(processors, processSuddenImpulse)
+	}
+	if config.CACTus {
+		processors = append(processors, processCACTus)
+	}
+	if config.MetOffice {
+		processors = append(processors, processMetOffice)
+	}
+	if config.BoMAPIKey != "" {
+		processors = append(processors, processBoMAurora)
+	}
+	if config.NICT {
+		processors = append(processors, processNICT)
+	}
+	if config.IMAPServer != "" {
+		processors = append(processors, processSWPCEmail)
+	}
+	for _, process := range processors {
+		severity, err := process(store)
+		if err != nil {
+			result.FetchFailed = true
+		}
+		if severity > result.Severity {
+			result.Severity = severity
+		}
+	}
+	readings := currentReadings()
+	pushZabbix(readings)
+	if err := store.Put("readings", readings); err != nil {
+		log.Println("Error saving readings:", err)
+	}
+	hist := updateReadingHistory(store, readings, time.Now().UTC())
+	notify(store, evaluateRecurrence(hist, time.Now().UTC()))
+	if err := store.Put("source_health", healthSnapshot()); err != nil {
+		log.Println("Error saving source health:", err)
+	}
+	notifications.releaseUndelivered(store)
+	if err := store.Flush(); err != nil {
+		log.Println("Error saving state:", err)
+	}
+	return result
+}
+
+// shutdown sends what is still queued, for up to notify_timeout_seconds,
+// and saves state with the claims of anything undelivered given back
+func shutdown(store StateStore) {
+	notifications.drain(time.Duration(config.NotifyTimeout) * time.Second)
+	notifications.releaseUndelivered(store)
+	if err := store.Flush(); err != nil {
+		log.Println("Error saving state:", err)
+	}
+}
+
+// popFlag removes a global flag from os.Args and reports whether it was set
+func popFlag(name string) bool {
+	for i, arg := range os.Args[1:] {
+		if arg == name {
+			os.Args = append(os.Args[:i+1], os.Args[i+2:]...)
+			return true
 		}
 	}
+	return false
 }
 
 func main() {
+	if popFlag("--debug-http") {
+		enableHTTPDebug()
+	}
+	once := popFlag("--once")
+
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfigCommand(os.Args[2:])
+		return
+	}
+
 	loadConfig()
+	setupLogging()
 
-	if len(os.Args) > 1 && os.Args[1] == "--test" {
-		log.Println("Running in test mode – sending test SMS...")
-		testMessage := "🚨 Test Alert: Space weather alert system is operational."
-		err := sendSMS(testMessage)
-		if err != nil {
-			log.Fatalf("Failed to send test SMS: %v", err)
-		} else {
-			log.Println("Test SMS sent successfully.")
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "--test":
+			log.Println("Running in test mode – sending test SMS...")
+			testMessage := "🚨 Test Alert: Space weather alert system is operational."
+			err := sendSMS(testMessage)
+			if err != nil {
+				log.Fatalf("Failed to send test SMS: %v", err)
+			} else {
+				log.Println("Test SMS sent successfully.")
+			}
+			return
+		case "status":
+			runStatus(os.Args[2:])
+			return
+		case "simulate":
+			runSimulate(os.Args[2:])
+			return
+		case "render":
+			runRender(os.Args[2:])
+			return
+		case "history":
+			runHistory(os.Args[2:])
+			return
+		case "doctor":
+			runDoctor(os.Args[2:])
+			return
+		case "check":
+			runCheck(os.Args[2:])
+			return
+		case "watch":
+			runWatch(os.Args[2:])
+			return
 		}
-		return
 	}
 
-	cache := loadAlertCache()
+	store := newStateStore()
+	if err := store.Get("control", &control); err != nil {
+		log.Println("Error loading control state:", err)
+	}
+	openEventLog()
+	openJSONL()
+	notifications = startDispatcher(configuredNotifiers())
+	startHTTPServer()
+	startControlListener(store)
 	log.Println("Starting space weather alert monitor...")
 	if config.DryRun {
 		log.Println("Running in dry-run mode. No SMS will be sent.")
 	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
 	for {
-		processSWPCAlerts(cache)
-		processKpIndex(cache)
-		processBzField(cache)
-		saveAlertCache(cache)
-		time.Sleep(time.Duration(config.CheckInterval) * time.Minute)
+		if currentControl().paused(time.Now()) {
+			log.Println("Monitoring paused; skipping poll")
+			markLoop(false)
+			if once {
+				os.Exit(exitOK)
+			}
+		} else {
+			markLoop(false)
+			result := poll(store)
+			markLoop(true)
+			if once {
+				shutdown(store)
+				log.Printf("Single pass complete: %s, fetch failed: %v", result.Severity, result.FetchFailed)
+				os.Exit(result.exitCode())
+			}
+		}
+		select {
+		case sig := <-stop:
+			log.Printf("Received %s, shutting down", sig)
+			shutdown(store)
+			os.Exit(exitOK)
+		case <-time.After(time.Duration(config.CheckInterval) * time.Minute):
+		}
 	}
 }
@@ -0,0 +1,268 @@
+// state_object.go
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// objectStore persists state to an S3-compatible bucket (S3, or GCS via its
+// interoperability API and HMAC keys). A local fileStore serves as the cache,
+// so a bucket outage only delays uploads until the next Flush.
+type objectStore struct {
+	local  *fileStore
+	bucket *bucketClient
+	prefix string
+
+	mu    sync.Mutex // guards docs and dirty
+	docs  map[string][]byte
+	dirty map[string]bool
+}
+
+func newObjectStore(provider string) *objectStore {
+	if config.StateBucket == "" {
+		log.Fatalf("state_backend %q requires state_bucket", provider)
+	}
+	endpoint := config.StateBucketEndpoint
+	region := config.StateBucketRegion
+	switch provider {
+	case "gcs":
+		if endpoint == "" {
+			endpoint = "https://storage.googleapis.com"
+		}
+		if region == "" {
+			region = "auto"
+		}
+	default:
+		if region == "" {
+			region = "us-east-1"
+		}
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", region)
+		}
+	}
+	accessKey := config.StateBucketAccessKey
+	secretKey := config.StateBucketSecretKey
+	if accessKey == "" {
+		accessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+		secretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	s := &objectStore{
+		local: newFileStore(),
+		bucket: &bucketClient{
+			endpoint:     strings.TrimRight(endpoint, "/"),
+			bucket:       config.StateBucket,
+			region:       region,
+			accessKey:    accessKey,
+			secretKey:    secretKey,
+			sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+		},
+		prefix: config.StateBucketPrefix,
+		docs:   make(map[string][]byte),
+		dirty:  make(map[string]bool),
+	}
+
+	data, err := s.bucket.get(s.prefix + "alert-cache.json")
+	if err != nil {
+		log.Println("Error loading state from bucket, using local cache:", err)
+	} else if data != nil {
+		remote := make(AlertCache)
+		if err := json.Unmarshal(data, &remote); err != nil {
+			log.Println("Error parsing bucket alert cache:", err)
+		}
+		for k := range remote {
+			s.local.cache[k] = true
+		}
+	}
+	return s
+}
+
+func (s *objectStore) Claim(key string) bool {
+	return s.local.Claim(key)
+}
+
+func (s *objectStore) Release(key string) {
+	s.local.Release(key)
+}
+
+func (s *objectStore) Get(name string, v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.docs[name]
+	if !ok {
+		var err error
+		data, err = s.bucket.get(s.prefix + "state-" + name + ".json")
+		if err != nil {
+			log.Printf("Error loading %s from bucket, using local cache: %v", name, err)
+			return s.local.Get(name, v)
+		}
+		s.docs[name] = data
+	}
+	if data == nil {
+		return nil
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *objectStore) Put(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[name] = data
+	s.dirty[name] = true
+	return s.local.Put(name, v)
+}
+
+func (s *objectStore) Flush() error {
+	s.local.Flush()
+	s.local.mu.Lock()
+	data, err := json.Marshal(s.local.cache)
+	s.local.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.bucket.put(s.prefix+"alert-cache.json", data); err != nil {
+		return err
+	}
+	for name := range s.dirty {
+		if err := s.bucket.put(s.prefix+"state-"+name+".json", s.docs[name]); err != nil {
+			return err
+		}
+		delete(s.dirty, name)
+	}
+	return nil
+}
+
+// bucketClient signs path-style object requests with AWS Signature V4
+type bucketClient struct {
+	endpoint     string
+	bucket       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+}
+
+// get returns nil data without error if the object doesn't exist
+func (b *bucketClient) get(key string) ([]byte, error) {
+	resp, err := b.do("GET", key, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", key, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (b *bucketClient) put(key string, data []byte) error {
+	resp, err := b.do("PUT", key, data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("PUT %s: %s", key, resp.Status)
+	}
+	return nil
+}
+
+func (b *bucketClient) do(method, key string, body []byte) (*http.Response, error) {
+	path := "/" + b.bucket + "/" + awsURIEscape(key)
+	req, err := http.NewRequest(method, b.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	day := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if b.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", b.sessionToken)
+	}
+	if method == "PUT" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(req.Header.Get(name))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash,
+	}, "\n")
+	scope := day + "/" + b.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key0 := hmacSHA256([]byte("AWS4"+b.secretKey), day)
+	key0 = hmacSHA256(key0, b.region)
+	key0 = hmacSHA256(key0, "s3")
+	key0 = hmacSHA256(key0, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key0, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		b.accessKey, scope, signedHeaders, signature))
+	return httpClient.Do(req)
+}
+
+func sha256Hex(data []byte) string {
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	m := hmac.New(sha256.New, key)
+	m.Write([]byte(data))
+	return m.Sum(nil)
+}
+
+// awsURIEscape encodes everything except unreserved characters and '/'
+func awsURIEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+// state_redis.go
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// redisStore keeps state in Redis so several instances can share it
+type redisStore struct {
+	client *redisClient
+	prefix string
+}
+
+func newRedisStore() *redisStore {
+	return &redisStore{
+		client: &redisClient{addr: config.RedisAddr, password: config.RedisPassword, db: config.RedisDB},
+		prefix: config.RedisKeyPrefix,
+	}
+}
+
+// Claim uses SET NX so only one instance sends a given alert, expiring the
+// claim after redis_claim_ttl_days so old keys don't pile up. If Redis is
+// unreachable it fails open: a duplicate SMS beats a missed one.
+func (s *redisStore) Claim(key string) bool {
+	args := []string{"SET", s.prefix + "sent:" + key, "1", "NX"}
+	if config.RedisClaimTTLDays > 0 {
+		args = append(args, "EX", strconv.Itoa(config.RedisClaimTTLDays*24*60*60))
+	}
+	reply, err := s.client.do(args...)
+	if err != nil {
+		log.Println("Redis error:", err)
+		return true
+	}
+	return reply != nil
+}
+
+func (s *redisStore) Release(key string) {
+	if _, err := s.client.do("DEL", s.prefix+"sent:"+key); err != nil {
+		log.Println("Redis error:", err)
+	}
+}
+
+func (s *redisStore) Get(name string, v interface{}) error {
+	reply, err := s.client.do("GET", s.prefix+"doc:"+name)
+	if err != nil || reply == nil {
+		return err
+	}
+	data, ok := reply.(string)
+	if !ok {
+		return fmt.Errorf("unexpected redis reply %T", reply)
+	}
+	return json.Unmarshal([]byte(data), v)
+}
+
+func (s *redisStore) Put(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.do("SET", s.prefix+"doc:"+name, string(data))
+	return err
+}
+
+func (s *redisStore) Flush() error {
+	return nil
+}
+
+// redisClient is a minimal RESP client holding a single connection
+type redisClient struct {
+	addr     string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+func (c *redisClient) do(args ...string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return nil, err
+		}
+	}
+	reply, err := c.roundTrip(args)
+	var redisErr redisError
+	if err != nil && !errors.As(err, &redisErr) {
+		c.conn.Close()
+		c.conn = nil
+	}
+	return reply, err
+}
+
+func (c *redisClient) connect() error {
+	conn, err := net.DialTimeout("tcp", c.addr, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.rd = bufio.NewReader(conn)
+	if c.password != "" {
+		if _, err := c.roundTrip([]string{"AUTH", c.password}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return err
+		}
+	}
+	if c.db != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(c.db)}); err != nil {
+			conn.Close()
+			c.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *redisClient) roundTrip(args []string) (interface{}, error) {
+	c.conn.SetDeadline(time.Now().Add(10 * time.Second))
+	buf := []byte(fmt.Sprintf("*%d\r\n", len(args)))
+	for _, a := range args {
+		buf = append(buf, fmt.Sprintf("$%d\r\n%s\r\n", len(a), a)...)
+	}
+	if _, err := c.conn.Write(buf); err != nil {
+		return nil, err
+	}
+	return readRESP(c.rd)
+}
+
+type redisError string
+
+func (e redisError) Error() string { return "redis: " + string(e) }
+
+// readRESP decodes one reply: strings, integers, nil or []interface{}
+func readRESP(rd *bufio.Reader) (interface{}, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 {
+		return nil, fmt.Errorf("malformed redis reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, redisError(body)
+	case ':':
+		return strconv.ParseInt(body, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, err
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readRESP(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unknown redis reply type %q", kind)
+}
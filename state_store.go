@@ -0,0 +1,97 @@
+// state_store.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+)
+
+// StateStore persists dedup state and small JSON documents between polls
+type StateStore interface {
+	// Claim records key and reports whether it was not already recorded
+	Claim(key string) bool
+	// Release forgets a claimed key, for alerts that could not be queued
+	Release(key string)
+	// Get decodes the named document into v, leaving v untouched if absent
+	Get(name string, v interface{}) error
+	// Put stores v as the named document
+	Put(name string, v interface{}) error
+	// Flush writes any buffered state to the backend
+	Flush() error
+}
+
+func newStateStore() StateStore {
+	switch config.StateBackend {
+	case "", "file":
+		return newFileStore()
+	case "redis":
+		return newRedisStore()
+	case "s3", "gcs":
+		return newObjectStore(config.StateBackend)
+	default:
+		log.Fatalf("Unknown state_backend %q", config.StateBackend)
+		return nil
+	}
+}
+
+// fileStore keeps state in files in the working directory
+type fileStore struct {
+	mu    sync.Mutex // the control listener saves state between polls
+	cache AlertCache
+}
+
+func newFileStore() *fileStore {
+	return &fileStore{cache: loadAlertCache()}
+}
+
+func (s *fileStore) Claim(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache[key] {
+		return false
+	}
+	s.cache[key] = true
+	return true
+}
+
+func (s *fileStore) Release(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.cache, key)
+}
+
+func (s *fileStore) Get(name string, v interface{}) error {
+	data, err := ioutil.ReadFile(stateDocFile(name))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *fileStore) Put(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ioutil.WriteFile(stateDocFile(name), data, 0644)
+}
+
+func (s *fileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	saveAlertCache(s.cache)
+	return nil
+}
+
+func stateDocFile(name string) string {
+	return fmt.Sprintf(".swpc-state-%s.json", name)
+}
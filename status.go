@@ -0,0 +1,106 @@
+// status.go
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// runStatus prints the source health last persisted by the monitor
+func runStatus(args []string) {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	format := formatFlag(fs)
+	fs.Parse(args)
+
+	var snap HealthSnapshot
+	if err := newStateStore().Get("source_health", &snap); err != nil {
+		log.Fatalf("Failed to read status: %v", err)
+	}
+	var readings map[string]Reading
+	if err := newStateStore().Get("readings", &readings); err != nil {
+		log.Fatalf("Failed to read readings: %v", err)
+	}
+	if *format == "json" {
+		printJSON(struct {
+			HealthSnapshot
+			Readings map[string]Reading `json:"readings"`
+		}{snap, readings})
+		return
+	}
+	if snap.UpdatedAt.IsZero() {
+		fmt.Println("No status recorded yet; is the monitor running?")
+		return
+	}
+
+	now := time.Now().UTC()
+	fmt.Printf("Status as of %s (%s ago)\n\n", snap.UpdatedAt.Format(time.RFC3339), ago(now, snap.UpdatedAt))
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tSINCE SUCCESS\tLATENCY\tAVG LATENCY\tRESPONSES\tPARSE ERRORS\tDATA AGE\tLAST ERROR")
+	for _, name := range sortedSources(snap) {
+		h := snap.Sources[name]
+		avg := 0.0
+		if h.Fetches > 0 {
+			avg = h.LatencySum / float64(h.Fetches)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.2fs\t%.2fs\t%s\t%d\t%s\t%s\n",
+			name, ago(now, h.LastSuccess), h.LastLatency, avg,
+			formatStatusCounts(h.StatusCounts), h.ParseErrors, ago(now, h.NewestTimeTag), h.LastError)
+	}
+	tw.Flush()
+
+	if len(readings) == 0 {
+		return
+	}
+	metrics := make([]string, 0, len(readings))
+	for metric := range readings {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	fmt.Println()
+	fmt.Fprintln(tw, "METRIC\tVALUE\tOBSERVED\tSOURCE")
+	for _, metric := range metrics {
+		r := readings[metric]
+		fmt.Fprintf(tw, "%s\t%g\t%s ago\t%s\n", metric, r.Value, ago(now, r.Time), r.Source)
+	}
+	tw.Flush()
+}
+
+func formatStatusCounts(counts map[string]int) string {
+	codes := make([]string, 0, len(counts))
+	for code := range counts {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	parts := make([]string, len(codes))
+	for i, code := range codes {
+		parts[i] = fmt.Sprintf("%s:%d", code, counts[code])
+	}
+	return strings.Join(parts, " ")
+}
+
+func ago(now, t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return now.Sub(t).Round(time.Second).String()
+}
+
+// formatFlag registers the --format flag shared by the reporting commands
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "text", "output format: text or json")
+}
+
+func printJSON(v interface{}) {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Fatalf("Failed to encode output: %v", err)
+	}
+	fmt.Println(string(out))
+}
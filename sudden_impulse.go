@@ -0,0 +1,113 @@
+// sudden_impulse.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/url"
+	"time"
+)
+
+const usgsGeomagURL = "https://geomag.usgs.gov/ws/data/"
+
+// GeomagData is the USGS geomagnetism web service JSON response
+type GeomagData struct {
+	Times  []string `json:"times"`
+	Values []struct {
+		ID     string     `json:"id"`
+		Values []*float64 `json:"values"`
+	} `json:"values"`
+}
+
+// processSuddenImpulse checks one-minute H from a ground magnetometer for a
+// sudden impulse; this reacts within a poll, long before the 3-hourly K
+// index moves
+func processSuddenImpulse(store StateStore) (Severity, error) {
+	now := time.Now().UTC()
+	// overlap the previous poll so a jump between polls is not missed; dedup
+	// drops the repeat
+	start := now.Add(-time.Duration(config.CheckInterval+10) * time.Minute)
+	q := url.Values{}
+	q.Set("id", config.SIObservatory)
+	q.Set("elements", "H")
+	q.Set("sampling_period", "60")
+	q.Set("format", "json")
+	q.Set("starttime", start.Format("2006-01-02T15:04:05Z"))
+	q.Set("endtime", now.Format("2006-01-02T15:04:05Z"))
+	var data GeomagData
+	if err := fetchJSON("usgs_geomag", usgsGeomagURL+"?"+q.Encode(), &data); err != nil {
+		log.Println("Error fetching magnetometer data:", err)
+		return SeverityOK, err
+	}
+	samples := geomagSamples(data, "H")
+	if len(samples) == 0 {
+		err := fmt.Errorf("no H readings from %s", config.SIObservatory)
+		log.Println("Error fetching magnetometer data:", err)
+		return SeverityOK, err
+	}
+	recordTimeTag("usgs_geomag", samples[len(samples)-1].TimeTag)
+	notes, peak := evaluateSuddenImpulse(samples)
+	recordReading("dbdt", "usgs_geomag", math.Round(peak*10)/10, samples[len(samples)-1].TimeTag)
+	notify(store, notes)
+	return activeSeverity(notes), nil
+}
+
+// geomagSamples pairs the times with one element's values, skipping gaps
+func geomagSamples(data GeomagData, element string) []Sample {
+	var samples []Sample
+	for _, v := range data.Values {
+		if v.ID != element {
+			continue
+		}
+		for i, value := range v.Values {
+			if value != nil && i < len(data.Times) {
+				samples = append(samples, Sample{TimeTag: data.Times[i], Value: *value})
+			}
+		}
+	}
+	return samples
+}
+
+// evaluateSuddenImpulse alerts on the first minute of each run of one-minute
+// changes in H of at least sudden_impulse_nt_per_min, and returns the largest
+// |dB/dt| seen. Twice the threshold is critical.
+func evaluateSuddenImpulse(samples []Sample) ([]Notification, float64) {
+	var notes []Notification
+	peak := 0.0
+	inImpulse := false
+	for i := 1; i < len(samples); i++ {
+		prev, cur := samples[i-1], samples[i]
+		t0, err0 := parseTimeTag(prev.TimeTag)
+		t1, err1 := parseTimeTag(cur.TimeTag)
+		if err0 != nil || err1 != nil || t1.Sub(t0) != time.Minute {
+			inImpulse = false
+			continue
+		}
+		dbdt := cur.Value - prev.Value
+		peak = math.Max(peak, math.Abs(dbdt))
+		if config.SIThreshold <= 0 || math.Abs(dbdt) < config.SIThreshold {
+			inImpulse = false
+			continue
+		}
+		if inImpulse {
+			continue
+		}
+		inImpulse = true
+		severity := SeverityWarning
+		if math.Abs(dbdt) >= 2*config.SIThreshold {
+			severity = SeverityCritical
+		}
+		msg := renderMessage("sudden_impulse", map[string]interface{}{
+			"DBDt": dbdt, "Observatory": config.SIObservatory, "TimeTag": cur.TimeTag,
+		})
+		notes = append(notes, Notification{
+			Key:      hashAlert("sudden_impulse " + config.SIObservatory + " " + cur.TimeTag),
+			Rule:     "sudden_impulse",
+			Text:     msg,
+			Severity: severity,
+			Time:     t1,
+		})
+	}
+	return notes, peak
+}
@@ -0,0 +1,234 @@
+// summary.go
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	kIndexPattern     = regexp.MustCompile(`K-index of (\d)`)
+	xrayClassPattern  = regexp.MustCompile(`X-ray Class:\s*([A-Z]\d+(?:\.\d+)?)`)
+	auroraPattern     = regexp.MustCompile(`(?i)seen as low as (.+?)\.?$`)
+	watchDayPattern   = regexp.MustCompile(`([A-Z][a-z]{2} \d{1,2}):\s+([GSR][1-5])`)
+	bulletinKindNames = []string{"CANCEL", "EXTENDED WARNING", "WARNING", "WATCH", "ALERT", "SUMMARY"}
+)
+
+var scaleStrengths = []string{"", "Minor", "Moderate", "Strong", "Severe", "Extreme"}
+
+// impactPhrases turns the Potential Impacts headings into plain English;
+// headings not listed are left out of the summary
+var impactPhrases = map[string]string{
+	"induced currents":     "power grid disturbances",
+	"spacecraft":           "satellite problems",
+	"satellite operations": "satellite problems",
+	"navigation":           "GPS errors",
+	"radio":                "HF radio disruption",
+	"biological":           "radiation risk for astronauts and polar flights",
+}
+
+// summarizeSWPC condenses an SWPC bulletin into one or two plain-English
+// sentences: what is happening, how strong, when, and what it may affect.
+// It returns "" when the text does not look like a bulletin.
+func summarizeSWPC(msg string) string {
+	fields := make(map[string]string)
+	var kind, headline string
+	var impacts []string
+	inImpacts := false
+	for _, line := range strings.Split(strings.ReplaceAll(msg, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if inImpacts {
+			impacts = append(impacts, line)
+			continue
+		}
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		key, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if kind == "" && key == strings.ToUpper(key) {
+			for _, k := range bulletinKindNames {
+				if strings.HasPrefix(key, k) {
+					kind, headline = k, value
+					break
+				}
+			}
+		}
+		if key == "Potential Impacts" {
+			// the first impact sometimes shares the heading's line
+			impacts = append(impacts, value)
+			inImpacts = true
+			continue
+		}
+		if _, seen := fields[key]; !seen {
+			fields[key] = value
+		}
+	}
+	event, detail := bulletinEvent(headline, msg)
+	if kind == "" || event == "" {
+		return ""
+	}
+	scale := ""
+	if m := noaaScalePattern.FindString(fields["NOAA Scale"]); m != "" {
+		scale = m
+	} else if level := noaaScaleLevel(msg); level > 0 {
+		for _, m := range noaaScalePattern.FindAllString(msg, -1) {
+			if int(m[1]-'0') == level {
+				scale = m
+				break
+			}
+		}
+	}
+	if scale != "" {
+		event = fmt.Sprintf("%s (%s) %s", scaleStrengths[scale[1]-'0'], scale, event)
+	} else {
+		event = strings.ToUpper(event[:1]) + event[1:]
+	}
+
+	var sentence string
+	switch kind {
+	case "ALERT":
+		sentence = event + " under way"
+		for _, key := range []string{"Threshold Reached", "Begin Time", "Observed"} {
+			if t := fields[key]; t != "" {
+				sentence += " since " + bulletinTime(t)
+				break
+			}
+		}
+	case "WARNING", "EXTENDED WARNING":
+		sentence = event + " expected"
+		if from := fields["Valid From"]; from != "" {
+			sentence += " from " + bulletinTime(from)
+		}
+		to := fields["Valid To"]
+		if until := fields["Now Valid Until"]; until != "" {
+			to = until
+		}
+		if to != "" {
+			sentence += " until " + bulletinTime(to)
+		}
+	case "WATCH":
+		sentence = event + " watch"
+		var days []string
+		for _, m := range watchDayPattern.FindAllStringSubmatch(msg, -1) {
+			days = append(days, m[1])
+		}
+		if len(days) > 0 {
+			sentence += " for " + joinPlain(days)
+		}
+	case "SUMMARY":
+		sentence, detail = event+detail+" has ended", ""
+		if t := fields["Maximum Time"]; t != "" {
+			sentence += ", peaking at " + bulletinTime(t)
+		}
+	case "CANCEL":
+		sentence = "Cancelled: " + event + " no longer expected"
+	}
+	sentence += detail + "."
+
+	if kind != "CANCEL" {
+		if effects := bulletinImpacts(impacts); effects != "" {
+			sentence += " " + effects
+		}
+	}
+	return sentence
+}
+
+// bulletinEvent names the event in the headline, plus a short detail such
+// as the Kp or flare class
+func bulletinEvent(headline, msg string) (string, string) {
+	h := strings.ToLower(headline)
+	switch {
+	case strings.Contains(h, "k-index"):
+		detail := ""
+		if m := kIndexPattern.FindStringSubmatch(headline); m != nil {
+			detail = " (Kp " + m[1] + ")"
+		}
+		return "geomagnetic storm", detail
+	case strings.Contains(h, "geomagnetic storm"):
+		return "geomagnetic storm", ""
+	case strings.Contains(h, "sudden impulse"):
+		return "geomagnetic sudden impulse", " (a CME shock arriving)"
+	case strings.Contains(h, "proton"):
+		return "solar radiation storm", ""
+	case strings.Contains(h, "electron"):
+		return "high-energy electron event", ""
+	case strings.Contains(h, "x-ray"):
+		if m := xrayClassPattern.FindStringSubmatch(msg); m != nil {
+			article := "a"
+			if m[1][0] == 'M' || m[1][0] == 'X' {
+				article = "an"
+			}
+			return "radio blackout", fmt.Sprintf(" from %s %s solar flare", article, m[1])
+		}
+		return "radio blackout", " from a solar flare"
+	case strings.Contains(h, "radio"):
+		return "solar radio burst", ""
+	}
+	return strings.TrimSpace(strings.TrimSuffix(h, "predicted")), ""
+}
+
+// bulletinImpacts turns "Heading - description" impact lines into one
+// sentence
+func bulletinImpacts(lines []string) string {
+	var effects []string
+	aurora := ""
+	seen := make(map[string]bool)
+	for _, line := range lines {
+		parts := strings.SplitN(line, " - ", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		heading := strings.ToLower(strings.TrimSpace(parts[0]))
+		if heading == "aurora" {
+			if m := auroraPattern.FindStringSubmatch(strings.TrimSpace(parts[1])); m != nil {
+				aurora = "aurora may be seen as low as " + m[1]
+			}
+			continue
+		}
+		if phrase, ok := impactPhrases[heading]; ok && !seen[phrase] {
+			seen[phrase] = true
+			effects = append(effects, phrase)
+		}
+	}
+	switch {
+	case len(effects) > 0 && aurora != "":
+		return "Possible " + joinPlain(effects) + "; " + aurora + "."
+	case len(effects) > 0:
+		return "Possible " + joinPlain(effects) + "."
+	case aurora != "":
+		return strings.ToUpper(aurora[:1]) + aurora[1:] + "."
+	}
+	return ""
+}
+
+// bulletinTime shortens "2024 May 10 1800 UTC" to "18:00 UTC 10 May"
+func bulletinTime(s string) string {
+	t, err := time.Parse("2006 Jan 2 1504 UTC", s)
+	if err != nil {
+		return s
+	}
+	return t.Format("15:04 UTC 2 Jan")
+}
+
+func joinPlain(items []string) string {
+	if len(items) < 2 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// swpcSummary renders the swpc_summary template for SMS, or "" to send the
+// full bulletin
+func swpcSummary(msg string) string {
+	summary := summarizeSWPC(msg)
+	if summary == "" {
+		return ""
+	}
+	return strings.TrimSpace(renderMessage("swpc_summary", map[string]interface{}{"Summary": summary, "Message": msg}))
+}
@@ -0,0 +1,181 @@
+// swpc_email.go
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	swpcCodePattern   = regexp.MustCompile(`Space Weather Message Code:\s*(\S+)`)
+	swpcSerialPattern = regexp.MustCompile(`Serial Number:\s*(\d+)`)
+	swpcIssuePattern  = regexp.MustCompile(`Issue Time:\s*(\d{4} \w{3} \d{1,2} \d{4}) UTC`)
+)
+
+// swpcAlertKey identifies an SWPC product by message code and serial number,
+// so the same bulletin from the JSON feed and from email is sent once.
+// Text without them falls back to a hash of the whole message.
+func swpcAlertKey(msg string) string {
+	code := swpcCodePattern.FindStringSubmatch(msg)
+	serial := swpcSerialPattern.FindStringSubmatch(msg)
+	if code == nil || serial == nil {
+		return hashAlert(msg)
+	}
+	return hashAlert("swpc " + code[1] + " " + serial[1])
+}
+
+// processSWPCEmail reads unseen SWPC product subscription emails over IMAP
+// and runs them through the same rules as the alerts feed, as a backup for
+// when the JSON endpoints are down or late. Messages are only flagged seen
+// once their alerts have been queued, so a failure part way through, or an
+// alert dropped by a full queue, leaves the message to be read again next
+// poll.
+func processSWPCEmail(store StateStore) (Severity, error) {
+	start := time.Now()
+	c, err := openSWPCMailbox()
+	if err != nil {
+		recordFetch("swpc_email", "error", time.Since(start), false, err)
+		log.Println("Error reading SWPC emails:", err)
+		return SeverityOK, err
+	}
+	defer c.Close()
+	alerts, read, keys, err := fetchSWPCEmails(c)
+	status := "ok"
+	if err != nil {
+		status = "error"
+		log.Println("Error reading SWPC emails:", err)
+	}
+	recordFetch("swpc_email", status, time.Since(start), false, err)
+	for _, alert := range alerts {
+		recordTimeTag("swpc_email", alert.IssueDatetime)
+	}
+	notes := evaluateSWPCAlerts(alerts)
+	dropped := notify(store, notes)
+	for _, uid := range read {
+		if dropped[keys[uid]] {
+			log.Printf("Leaving email %s unseen: its alert could not be queued", uid)
+			continue
+		}
+		if err := c.MarkSeen(uid); err != nil {
+			log.Printf("Error flagging email %s as seen: %v", uid, err)
+			break
+		}
+	}
+	return activeSeverity(notes), err
+}
+
+func openSWPCMailbox() (*imapConn, error) {
+	c, err := dialIMAP(config.IMAPServer)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Login(config.IMAPUsername, config.IMAPPassword); err != nil {
+		c.Close()
+		return nil, err
+	}
+	if err := c.Select(config.IMAPMailbox); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// fetchSWPCEmails returns the SWPC products in the matching messages, the
+// UIDs of every message read and the alert key of each product's message,
+// stopping at the first failure
+func fetchSWPCEmails(c *imapConn) ([]Alert, []string, map[string]string, error) {
+	uids, err := c.Search(config.IMAPSearch)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var alerts []Alert
+	var read []string
+	keys := make(map[string]string)
+	for _, uid := range uids {
+		raw, err := c.Fetch(uid)
+		if err != nil {
+			return alerts, read, keys, err
+		}
+		read = append(read, uid)
+		if alert, ok := parseSWPCEmail(raw); ok {
+			alerts = append(alerts, alert)
+			keys[uid] = swpcAlertKey(alert.Message)
+		} else {
+			log.Printf("Skipping email %s: no SWPC product in it", uid)
+		}
+	}
+	return alerts, read, keys, nil
+}
+
+// parseSWPCEmail extracts the product text from a subscription email, from
+// the message code line onwards
+func parseSWPCEmail(raw []byte) (Alert, bool) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return Alert{}, false
+	}
+	body, err := textBody(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body)
+	if err != nil {
+		return Alert{}, false
+	}
+	i := strings.Index(body, "Space Weather Message Code:")
+	if i < 0 {
+		return Alert{}, false
+	}
+	text := strings.TrimSpace(strings.ReplaceAll(body[i:], "\r\n", "\n"))
+	alert := Alert{Message: text}
+	if m := swpcIssuePattern.FindStringSubmatch(text); m != nil {
+		if t, err := time.Parse("2006 Jan 2 1504", m[1]); err == nil {
+			alert.IssueDatetime = t.Format("2006-01-02 15:04:05.000")
+		}
+	}
+	return alert, true
+}
+
+// textBody returns the first text/plain part of a message body, decoded
+func textBody(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := mr.NextPart()
+			if err != nil {
+				return "", fmt.Errorf("no text/plain part: %v", err)
+			}
+			text, err := textBody(part.Header.Get("Content-Type"), part.Header.Get("Content-Transfer-Encoding"), part)
+			if err == nil && text != "" {
+				return text, nil
+			}
+		}
+	}
+	if mediaType != "text/plain" {
+		return "", nil
+	}
+	if strings.EqualFold(encoding, "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	data, err := ioutil.ReadAll(body)
+	return string(data), err
+}
+
+// checkIMAP logs in and opens the mailbox without reading anything
+func checkIMAP() error {
+	c, err := openSWPCMailbox()
+	if err != nil {
+		return err
+	}
+	return c.Close()
+}
@@ -0,0 +1,75 @@
+// templates.go
+package main
+
+import (
+	"bytes"
+	"log"
+	"text/template"
+)
+
+// defaultTemplates holds the built-in message per rule; entries in the
+// templates config map override them
+var defaultTemplates = map[string]string{
+	"swpc_alert":       "🌐 SWPC Alert: {{.Message}}",
+	"kp":               "🧠 K-index Alert: Kp = {{printf \"%.2f\" .Kp}} at {{.TimeTag}} (24 h max {{printf \"%.2f\" .Max24h}})\nLinked to sleep disruption, anxiety, and focus issues.",
+	"bz":               "🧠 Geomagnetic Instability Alert: Bz = {{printf \"%.2f\" .Bz}} nT at {{.TimeTag}} (24 h min {{printf \"%.2f\" .Min24h}})\nMay disrupt sleep, mood, or focus in sensitive individuals.",
+	"bz_flip":          "🧭 Bz turned southward: {{printf \"%+.1f\" .From}} → {{printf \"%+.1f\" .To}} nT between {{.FromTimeTag}} and {{.TimeTag}}\nRapid southward turnings often precede storm intensification.",
+	"sudden_impulse":   "⚡ Sudden impulse: H changed {{printf \"%+.1f\" .DBDt}} nT in one minute at {{.Observatory}} ({{.TimeTag}})\nA CME shock has likely just reached Earth.",
+	"cme":              "☄️ {{.Kind}} CME detected: {{printf \"%.0f\" .Speed}} km/s, {{printf \"%.0f\" .Width}}° wide, onset {{.Onset}} UTC\nPossibly Earth-directed; arrival would be in 1–3 days.",
+	"metoffice":        "🇬🇧 Met Office Space Weather: {{.Message}}",
+	"nict":             "🇯🇵 NICT Space Weather: {{.Message}}",
+	"bom_aurora":       "🇦🇺 Aurora {{.Kind}} (K-aus {{.KAus}}, {{.LatBand}} latitudes) from {{.Start}}\n{{.Detail}}",
+	"flare_onset":      "🔆 {{.Class}} flare starting now: X-ray flux {{printf \"%.2g\" .Flux}} W/m² and rising at {{.TimeTag}}\nRadio blackouts on the sunlit side are possible within minutes.",
+	"recurrence":       "🔁 27-day recurrence: Kp reached {{printf \"%.1f\" .Kp}} on {{.Day}}; similar activity is possible around {{.Date}} as the same region rotates back into view.",
+	"swpc_summary":     "🌐 SWPC: {{.Summary}}",
+	"electron_fluence": "🛰️ Electron fluence: >2 MeV daily fluence reached {{printf \"%.2g\" .Fluence}} e/cm²/sr on {{.Day}} (through {{.Through}} UTC, flux now {{printf \"%.0f\" .Flux}} pfu)\nSpacecraft internal charging risk is elevated.",
+	"sep_hardness":     "☢️ Hard-spectrum proton event: ≥100 MeV flux {{printf \"%.2g\" .Flux100}} pfu, {{printf \"%.0f\" .Percent}}% of ≥10 MeV ({{printf \"%.3g\" .Flux10}} pfu; ≥50 MeV {{printf \"%.3g\" .Flux50}} pfu) at {{.TimeTag}}\nHigh radiation risk for spacewalks, polar flights and avionics; a ground level enhancement is possible.",
+}
+
+// templateSamples are the values the render command starts from
+var templateSamples = map[string]map[string]interface{}{
+	"swpc_alert":       {"Message": "ALERT: Geomagnetic K-index of 7\nNOAA Scale: G3 - Strong"},
+	"kp":               {"Kp": 7.33, "TimeTag": "2024-05-10T18:00:00", "Min24h": 3.0, "Max24h": 8.67},
+	"bz":               {"Bz": -12.4, "TimeTag": "2024-05-10 18:00:00.000", "Min24h": -18.1, "Max24h": 6.2},
+	"bom_aurora":       {"Kind": "alert", "KAus": 6.0, "LatBand": "high", "Start": "2024-05-10 18:00:00", "Detail": "Aurora may be visible from Tasmania and the coastline of Victoria."},
+	"bz_flip":          {"From": 9.2, "To": -11.5, "FromTimeTag": "2024-05-10 17:42:00.000", "TimeTag": "2024-05-10 18:00:00.000"},
+	"sudden_impulse":   {"DBDt": 23.4, "Observatory": "BOU", "TimeTag": "2024-05-10T17:05:00.000Z"},
+	"cme":              {"Kind": "Halo", "Speed": 1250.0, "Width": 360.0, "Angle": 0.0, "Onset": "2024-05-10 18:00"},
+	"nict":             {"Message": "Geomagnetic activity is expected to reach minor storm levels within the next 24 hours."},
+	"metoffice":        {"Message": "Strong (G3) geomagnetic storm intervals are likely on day 1 as the CME arrives."},
+	"flare_onset":      {"Class": "M2.3", "Flux": 2.3e-5, "TimeTag": "2024-05-10T17:58:00Z"},
+	"recurrence":       {"Kp": 7.0, "Day": "2024-05-10", "Date": "Thu 6 Jun"},
+	"swpc_summary":     {"Summary": "Strong (G3) geomagnetic storm under way since 17:54 UTC 10 May (Kp 7). Possible power grid disturbances and GPS errors.", "Message": "ALERT: Geomagnetic K-index of 7\nNOAA Scale: G3 - Strong"},
+	"electron_fluence": {"Fluence": 1.3e9, "Flux": 4200.0, "Day": "2024-05-12", "Through": "18:00"},
+	"sep_hardness":     {"Flux10": 120.0, "Flux50": 35.0, "Flux100": 14.0, "Percent": 11.7, "TimeTag": "2024-05-11T06:00:00Z"},
+}
+
+func messageTemplate(rule string) string {
+	if t, ok := config.Templates[rule]; ok {
+		return t
+	}
+	return defaultTemplates[rule]
+}
+
+func executeTemplate(text string, data map[string]interface{}) (string, error) {
+	tmpl, err := template.New("message").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// renderMessage renders the template for rule, falling back to the built-in
+// template if a configured one is broken
+func renderMessage(rule string, data map[string]interface{}) string {
+	msg, err := executeTemplate(messageTemplate(rule), data)
+	if err != nil {
+		log.Printf("Template for %s failed, using default: %v", rule, err)
+		msg, _ = executeTemplate(defaultTemplates[rule], data)
+	}
+	return msg
+}
@@ -0,0 +1,87 @@
+// thresholds.go
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// ThresholdSchedule overrides the warning thresholds during a daily window
+// of local time; a zero threshold leaves that one unchanged
+type ThresholdSchedule struct {
+	From        string  `json:"from" desc:"Start of the window, HH:MM local time"`
+	To          string  `json:"to" desc:"End of the window, HH:MM; may be earlier than from to span midnight"`
+	KpThreshold float64 `json:"kp_threshold" desc:"Kp threshold inside the window"`
+	BzThreshold float64 `json:"bz_threshold" desc:"Bz threshold (nT) inside the window"`
+}
+
+// thresholdsAt returns the Kp and Bz warning thresholds in effect at t,
+// from the first matching schedule or else the top-level settings
+func thresholdsAt(t time.Time) (kp, bz float64) {
+	kp, bz = config.KpThreshold, config.BzThreshold
+	local := t.In(configLocation())
+	minute := local.Hour()*60 + local.Minute()
+	for _, s := range config.ThresholdSchedules {
+		from, err1 := parseClock(s.From)
+		to, err2 := parseClock(s.To)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		inside := from <= minute && minute < to
+		if from > to {
+			inside = minute >= from || minute < to
+		}
+		if !inside {
+			continue
+		}
+		if s.KpThreshold != 0 {
+			kp = s.KpThreshold
+		}
+		if s.BzThreshold != 0 {
+			bz = s.BzThreshold
+		}
+		break
+	}
+	return kp, bz
+}
+
+// configLocation is the timezone setting, or the system zone when unset
+func configLocation() *time.Location {
+	if config.Timezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(config.Timezone)
+	if err != nil {
+		log.Printf("Unknown timezone %q, using local time: %v", config.Timezone, err)
+		return time.Local
+	}
+	return loc
+}
+
+// parseClock converts HH:MM to minutes after midnight
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("bad time of day %q, want HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// checkThresholdSchedules reports the first invalid schedule setting
+func checkThresholdSchedules() error {
+	if config.Timezone != "" {
+		if _, err := time.LoadLocation(config.Timezone); err != nil {
+			return err
+		}
+	}
+	for i, s := range config.ThresholdSchedules {
+		if _, err := parseClock(s.From); err != nil {
+			return fmt.Errorf("threshold_schedules[%d].from: %v", i, err)
+		}
+		if _, err := parseClock(s.To); err != nil {
+			return fmt.Errorf("threshold_schedules[%d].to: %v", i, err)
+		}
+	}
+	return nil
+}
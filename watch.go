@@ -0,0 +1,223 @@
+// watch.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	ansiClear      = "\033[H\033[2J"
+	ansiHideCursor = "\033[?25l"
+	ansiShowCursor = "\033[?25h"
+	ansiBold       = "\033[1m"
+	ansiDim        = "\033[2m"
+	ansiYellow     = "\033[33m"
+	ansiRed        = "\033[31m"
+	ansiReset      = "\033[0m"
+)
+
+// runWatch redraws a live view of what the running monitor has stored:
+// current readings with 24 h sparklines, alerts active in the last day and
+// the recent alert log
+func runWatch(args []string) {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	refresh := fs.Duration("refresh", 10*time.Second, "how often to redraw")
+	fs.Parse(args)
+	if *refresh <= 0 {
+		fmt.Fprintf(os.Stderr, "--refresh must be positive, got %s\n", *refresh)
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	fmt.Print(ansiHideCursor)
+	defer fmt.Print(ansiShowCursor)
+
+	store := newStateStore()
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		fmt.Print(ansiClear + renderWatch(store, time.Now().UTC()))
+		select {
+		case <-stop:
+			fmt.Println()
+			return
+		case <-ticker.C:
+		}
+		if _, cached := store.(*objectStore); cached {
+			// the bucket store caches documents for the life of the store
+			store = newStateStore()
+		}
+	}
+}
+
+func renderWatch(store StateStore, now time.Time) string {
+	width, height := terminalSize()
+	var readings map[string]Reading
+	hist := make(ReadingHistory)
+	var history []HistoryEntry
+	var snap HealthSnapshot
+	var ctl ControlState
+	var errs []string
+	for name, v := range map[string]interface{}{
+		"readings": &readings, "reading_history": &hist, "history": &history,
+		"source_health": &snap, "control": &ctl,
+	} {
+		if err := store.Get(name, v); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sSpace weather%s  %s", ansiBold, ansiReset, now.Local().Format("Mon 2 Jan 15:04:05"))
+	switch {
+	case ctl.paused(now):
+		fmt.Fprintf(&b, "  %sPAUSED%s", ansiYellow, ansiReset)
+	case ctl.muted(now):
+		fmt.Fprintf(&b, "  %sMUTED%s", ansiYellow, ansiReset)
+	}
+	if !snap.UpdatedAt.IsZero() {
+		fmt.Fprintf(&b, "  %slast poll %s ago%s", ansiDim, ago(now, snap.UpdatedAt), ansiReset)
+	}
+	b.WriteString("\n\n")
+
+	metrics := make([]string, 0, len(readings))
+	for metric := range readings {
+		metrics = append(metrics, metric)
+	}
+	sort.Strings(metrics)
+	sparkWidth := width - 48
+	if sparkWidth < 10 {
+		sparkWidth = 10
+	}
+	fmt.Fprintf(&b, "%s%-10s %12s %-18s %s%s\n", ansiBold, "METRIC", "NOW", "SOURCE", "LAST 24 H", ansiReset)
+	for _, metric := range metrics {
+		r := readings[metric]
+		fmt.Fprintf(&b, "%-10s %12s %-18s %s\n", metric, formatPerf(r.Value), r.Source,
+			sparkline(hist[metric], now.Add(-24*time.Hour), now, sparkWidth))
+	}
+	if len(metrics) == 0 {
+		b.WriteString("No readings yet; is the monitor running?\n")
+	}
+
+	b.WriteString("\n" + ansiBold + "ACTIVE (24 H)" + ansiReset + "\n")
+	type episode struct {
+		count int
+		last  time.Time
+	}
+	active := make(map[string]*episode)
+	var rules []string
+	for _, e := range history {
+		if now.Sub(e.Time) > 24*time.Hour {
+			continue
+		}
+		ep, ok := active[e.Rule]
+		if !ok {
+			ep = &episode{}
+			active[e.Rule] = ep
+			rules = append(rules, e.Rule)
+		}
+		ep.count++
+		ep.last = e.Time
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		ep := active[rule]
+		fmt.Fprintf(&b, "%s%-16s%s %3d alert(s), last %s ago\n", ansiRed, rule, ansiReset, ep.count, ago(now, ep.last))
+	}
+	if len(rules) == 0 {
+		b.WriteString("None\n")
+	}
+
+	b.WriteString("\n" + ansiBold + "ALERT LOG" + ansiReset + "\n")
+	used := strings.Count(b.String(), "\n")
+	room := height - used - 2
+	for i := len(history) - 1; i >= 0 && room > 0; i-- {
+		e := history[i]
+		line := strings.TrimSpace(strings.SplitN(e.Text, "\n", 2)[0])
+		prefix := e.Time.Local().Format("02 Jan 15:04") + "  "
+		if limit := width - len(prefix); limit > 0 && len([]rune(line)) > limit {
+			line = string([]rune(line)[:limit-1]) + "…"
+		}
+		fmt.Fprintf(&b, "%s%s%s%s\n", ansiDim, prefix, ansiReset, line)
+		room--
+	}
+	for _, e := range errs {
+		fmt.Fprintf(&b, "%s%s%s\n", ansiRed, e, ansiReset)
+	}
+	return b.String()
+}
+
+// sparkline draws samples between from and to as width block characters,
+// each the highest value in its slice of time, scaled to the range shown
+func sparkline(samples []HistorySample, from, to time.Time, width int) string {
+	const blocks = "▁▂▃▄▅▆▇█"
+	buckets := make([]float64, width)
+	filled := make([]bool, width)
+	lo, hi := 0.0, 0.0
+	first := true
+	for _, s := range samples {
+		if s.Time.Before(from) || s.Time.After(to) {
+			continue
+		}
+		i := int(float64(width-1) * float64(s.Time.Sub(from)) / float64(to.Sub(from)))
+		if !filled[i] || s.Value > buckets[i] {
+			buckets[i], filled[i] = s.Value, true
+		}
+		if first || s.Value < lo {
+			lo = s.Value
+		}
+		if first || s.Value > hi {
+			hi = s.Value
+		}
+		first = false
+	}
+	runes := []rune(blocks)
+	var b strings.Builder
+	for i := range buckets {
+		if !filled[i] {
+			b.WriteRune(' ')
+			continue
+		}
+		level := len(runes) - 1
+		if hi > lo {
+			level = int((buckets[i] - lo) / (hi - lo) * float64(len(runes)-1))
+		}
+		b.WriteRune(runes[level])
+	}
+	if !first {
+		fmt.Fprintf(&b, " %s%s–%s%s", ansiDim, formatPerf(lo), formatPerf(hi), ansiReset)
+	}
+	return b.String()
+}
+
+// terminalSize asks stty for the terminal size, then tries COLUMNS and
+// LINES, falling back to 80x24
+func terminalSize() (int, int) {
+	cmd := exec.Command("stty", "size")
+	cmd.Stdin = os.Stdin
+	if out, err := cmd.Output(); err == nil {
+		var rows, cols int
+		if _, err := fmt.Sscan(string(out), &rows, &cols); err == nil && rows > 0 && cols > 0 {
+			return cols, rows
+		}
+	}
+	width, height := 80, 24
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		width = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		height = n
+	}
+	return width, height
+}
@@ -0,0 +1,113 @@
+// zabbix.go
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// zabbixPushed remembers the newest reading sent per metric so unchanged
+// values aren't resent every poll
+var zabbixPushed = make(map[string]time.Time)
+
+type zabbixItem struct {
+	Host  string `json:"host"`
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Clock int64  `json:"clock"`
+}
+
+// pushZabbix sends new readings to zabbix_server as trapper items
+func pushZabbix(readings map[string]Reading) {
+	if config.ZabbixServer == "" {
+		return
+	}
+	var items []zabbixItem
+	for metric, r := range readings {
+		if !r.Time.After(zabbixPushed[metric]) {
+			continue
+		}
+		key := config.ZabbixKeys[metric]
+		if key == "" {
+			key = "swpc." + metric
+		}
+		items = append(items, zabbixItem{
+			Host:  config.ZabbixHost,
+			Key:   key,
+			Value: strconv.FormatFloat(r.Value, 'g', -1, 64),
+			Clock: r.Time.Unix(),
+		})
+	}
+	if len(items) == 0 {
+		return
+	}
+	info, err := zabbixSend(config.ZabbixServer, items)
+	if err != nil {
+		log.Println("Error sending to Zabbix:", err)
+		return
+	}
+	if !strings.Contains(info, "failed: 0") {
+		log.Println("Zabbix rejected some items:", info)
+	}
+	for metric, r := range readings {
+		if r.Time.After(zabbixPushed[metric]) {
+			zabbixPushed[metric] = r.Time
+		}
+	}
+}
+
+// zabbixSend speaks the sender/trapper protocol and returns the server's info
+func zabbixSend(addr string, items []zabbixItem) (string, error) {
+	if !strings.Contains(addr, ":") {
+		addr += ":10051"
+	}
+	payload, err := json.Marshal(map[string]interface{}{"request": "sender data", "data": items})
+	if err != nil {
+		return "", err
+	}
+	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	var packet bytes.Buffer
+	packet.WriteString("ZBXD\x01")
+	binary.Write(&packet, binary.LittleEndian, uint64(len(payload)))
+	packet.Write(payload)
+	if _, err := conn.Write(packet.Bytes()); err != nil {
+		return "", err
+	}
+
+	header := make([]byte, 13)
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return "", err
+	}
+	if string(header[:4]) != "ZBXD" {
+		return "", fmt.Errorf("unexpected Zabbix response header %q", header[:5])
+	}
+	body := make([]byte, binary.LittleEndian.Uint64(header[5:]))
+	if _, err := io.ReadFull(conn, body); err != nil {
+		return "", err
+	}
+	var resp struct {
+		Response string `json:"response"`
+		Info     string `json:"info"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", err
+	}
+	if resp.Response != "success" {
+		return "", fmt.Errorf("zabbix responded %q: %s", resp.Response, resp.Info)
+	}
+	return resp.Info, nil
+}